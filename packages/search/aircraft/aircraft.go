@@ -0,0 +1,105 @@
+package aircraft
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/tobyrushton/flyvia/packages/search/internal/csvdata"
+)
+
+type Body string
+
+const (
+	Widebody   Body = "widebody"
+	Narrowbody Body = "narrowbody"
+	// regional jets.
+	Regional  Body = "regional"
+	Turboprop Body = "turboprop"
+)
+
+type Aircraft struct {
+	// canonical name for the family, such as "A320neo" or "737 MAX".
+	Family string
+	Body   Body
+	// fuel burned per seat relative to the average airliner.
+	Efficiency float64
+}
+
+type entry struct {
+	match string
+	Aircraft
+}
+
+//go:embed aircraft.csv
+var aircraftCSV string
+
+// matched in order, so more specific names go first.
+var table = csvdata.Embed("aircraft", aircraftCSV, parse)
+
+// Load replaces the aircraft table with the one read from r, in the same csv
+// format as the embedded aircraft.csv. Rows are tried in order against the
+// provider's name for the plane, the first whose match it contains wins.
+func Load(r io.Reader) error {
+	return table.Load(r)
+}
+
+func parse(r io.Reader) ([]entry, error) {
+	t := make([]entry, 0)
+	err := csvdata.Rows(r, 4, func(line int, rec []string) error {
+		body := Body(rec[2])
+		switch body {
+		case Widebody, Narrowbody, Regional, Turboprop:
+		default:
+			return fmt.Errorf("line %d: unknown body %q", line, rec[2])
+		}
+		efficiency, err := strconv.ParseFloat(rec[3], 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid efficiency: %w", line, err)
+		}
+
+		t = append(t, entry{
+			match: strings.ToLower(rec[0]),
+			Aircraft: Aircraft{
+				Family:     rec[1],
+				Body:       body,
+				Efficiency: efficiency,
+			},
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Lookup normalises a provider's name for a plane, such as "Boeing 737MAX 8
+// Passenger", or a family name onto its family.
+func Lookup(plane string) (Aircraft, bool) {
+	plane = strings.ToLower(strings.TrimSpace(plane))
+	if plane == "" {
+		return Aircraft{}, false
+	}
+
+	entries := table.Get()
+	for _, e := range entries {
+		if strings.EqualFold(e.Family, plane) {
+			return e.Aircraft, true
+		}
+	}
+	for _, e := range entries {
+		if strings.Contains(plane, e.match) {
+			return e.Aircraft, true
+		}
+	}
+	return Aircraft{}, false
+}
+
+// Is reports whether a is the family or body named, ignoring case.
+func (a Aircraft) Is(name string) bool {
+	return strings.EqualFold(a.Family, name) || strings.EqualFold(string(a.Body), name)
+}
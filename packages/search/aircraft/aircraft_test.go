@@ -0,0 +1,91 @@
+package aircraft
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		plane  string
+		family string
+		body   Body
+	}{
+		{"Boeing 737MAX 8 Passenger", "737 MAX", Narrowbody},
+		{"Boeing 737-800", "737", Narrowbody},
+		{"Airbus A321neo", "A320neo", Narrowbody},
+		{"Airbus A321", "A320", Narrowbody},
+		{"Airbus A330-900neo", "A330neo", Widebody},
+		{"Boeing 787-9", "787", Widebody},
+		{"ATR 72", "ATR", Turboprop},
+		{"De Havilland Canada Dash 8-400", "Dash 8", Turboprop},
+		{"Embraer 190", "E-Jet", Regional},
+		{"737 max", "737 MAX", Narrowbody},
+	}
+
+	for _, tt := range tests {
+		a, ok := Lookup(tt.plane)
+		if !ok || a.Family != tt.family || a.Body != tt.body {
+			t.Errorf("%q: expected %s %s, got %+v", tt.plane, tt.family, tt.body, a)
+		}
+	}
+
+	if _, ok := Lookup("Zeppelin NT"); ok {
+		t.Error("expected an unknown plane not to be found")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	defer Load(strings.NewReader(aircraftCSV))
+
+	if err := Load(strings.NewReader("match,family,body,efficiency\nzeppelin,Zeppelin,airship,2\n")); err == nil {
+		t.Error("expected an error for an unknown body")
+	}
+	if err := Load(strings.NewReader("match,family,body,efficiency\nzeppelin,Zeppelin,widebody,2\n")); err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := Lookup("Zeppelin NT"); a.Family != "Zeppelin" {
+		t.Errorf("expected the loaded table to be used, got %+v", a)
+	}
+}
+
+func result(flights ...leg.Flight) search.Result {
+	return search.Result{Itineries: []itinery.Itinery{{Outbound: leg.Leg{Flights: flights}}}}
+}
+
+func TestAvoid(t *testing.T) {
+	results := []search.Result{
+		result(leg.Flight{Plane: "Airbus A320"}, leg.Flight{Plane: "ATR 72-600"}),
+		result(leg.Flight{Plane: "Airbus A320"}, leg.Flight{Plane: "Boeing 737MAX 8"}),
+		result(leg.Flight{Plane: "Airbus A320"}, leg.Flight{Plane: "Unknown"}),
+	}
+
+	if kept := Avoid(results, "turboprop"); len(kept) != 2 {
+		t.Errorf("expected the turboprop result to be dropped, got %d", len(kept))
+	}
+	if kept := Avoid(results, "turboprop", "737 max"); len(kept) != 1 {
+		t.Errorf("expected only the unknown plane result to be kept, got %d", len(kept))
+	}
+}
+
+func TestPreferenceShare(t *testing.T) {
+	r := result(
+		leg.Flight{DepartureAirport: "LHR", ArrivalAirport: "DOH", Plane: "Boeing 787-8"},
+		leg.Flight{DepartureAirport: "DOH", ArrivalAirport: "BKK", Plane: "Airbus A320"},
+		leg.Flight{DepartureAirport: "BKK", ArrivalAirport: "HKT", Plane: "Airbus A320"},
+	)
+
+	p := Preference{Aircraft: "widebody", MinKm: 4000}
+	if share := p.Share(r); share != 0.5 {
+		t.Errorf("expected one of the two long-haul flights on a widebody, got %v", share)
+	}
+
+	p.MinKm = 0
+	if share := p.Share(r); share < 0.33 || share > 0.34 {
+		t.Errorf("expected one of the three flights on a widebody, got %v", share)
+	}
+}
@@ -0,0 +1,74 @@
+package aircraft
+
+import (
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/airport"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+)
+
+// Of finds the aircraft flying f, using the family set by the provider when
+// there is one.
+func Of(f leg.Flight) (Aircraft, bool) {
+	if a, ok := Lookup(f.Aircraft); ok {
+		return a, true
+	}
+	return Lookup(f.Plane)
+}
+
+// Avoid drops the results with any flight on an aircraft of the given
+// families or bodies, such as "turboprop" or "737 MAX". Flights on unknown
+// aircraft are kept.
+func Avoid(results []search.Result, avoid ...string) []search.Result {
+	kept := make([]search.Result, 0, len(results))
+	for _, r := range results {
+		if !flies(r, avoid) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+func flies(r search.Result, names []string) bool {
+	for _, f := range r.Flights() {
+		a, ok := Of(f)
+		if !ok {
+			continue
+		}
+		for _, name := range names {
+			if a.Is(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Preference is an aircraft family or body to prefer, on flights at least
+// MinKm long, so widebodies can be preferred only on long-haul.
+type Preference struct {
+	Aircraft string
+	MinKm    float64
+}
+
+// Share is the fraction of r's flights the preference applies to that are on
+// the preferred aircraft, zero when it applies to none.
+func (p Preference) Share(r search.Result) float64 {
+	applies, preferred := 0, 0
+	for _, f := range r.Flights() {
+		if p.MinKm > 0 {
+			km, ok := airport.Distance(f.DepartureAirport, f.ArrivalAirport)
+			if !ok || km < p.MinKm {
+				continue
+			}
+		}
+		applies++
+		if a, ok := Of(f); ok && a.Is(p.Aircraft) {
+			preferred++
+		}
+	}
+
+	if applies == 0 {
+		return 0
+	}
+	return float64(preferred) / float64(applies)
+}
@@ -0,0 +1,142 @@
+package airline
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/tobyrushton/flyvia/packages/search/internal/csvdata"
+)
+
+type Alliance string
+
+const (
+	Oneworld     Alliance = "oneworld"
+	StarAlliance Alliance = "star-alliance"
+	SkyTeam      Alliance = "skyteam"
+)
+
+type Airline struct {
+	IATA string
+	ICAO string
+	Name string
+	// empty when the airline isn't in one.
+	Alliance Alliance
+	LowCost  bool
+}
+
+//go:embed airlines.csv
+var airlinesCSV string
+
+type dataset struct {
+	byCode map[string]Airline
+	// by normalised name and alias, to IATA code.
+	byName map[string]string
+}
+
+var data = csvdata.Embed("airline", airlinesCSV, parse)
+
+// Load replaces the airline dataset with the one read from r, in the same csv
+// format as the embedded airlines.csv. Aliases are separated by semicolons.
+func Load(r io.Reader) error {
+	return data.Load(r)
+}
+
+func parse(r io.Reader) (dataset, error) {
+	d := dataset{
+		byCode: make(map[string]Airline),
+		byName: make(map[string]string),
+	}
+	err := csvdata.Rows(r, 6, func(line int, rec []string) error {
+		a := Airline{
+			IATA:     strings.ToUpper(rec[0]),
+			ICAO:     strings.ToUpper(rec[1]),
+			Name:     rec[2],
+			Alliance: Alliance(rec[4]),
+		}
+
+		switch a.Alliance {
+		case "", Oneworld, StarAlliance, SkyTeam:
+		default:
+			return fmt.Errorf("line %d: unknown alliance %q", line, rec[4])
+		}
+		switch rec[5] {
+		case "0":
+		case "1":
+			a.LowCost = true
+		default:
+			return fmt.Errorf("line %d: invalid low cost flag %q", line, rec[5])
+		}
+
+		d.byCode[a.IATA] = a
+		if a.ICAO != "" {
+			d.byCode[a.ICAO] = a
+		}
+		d.byName[normalise(a.Name)] = a.IATA
+		for _, alias := range strings.Split(rec[3], ";") {
+			if alias = normalise(alias); alias != "" {
+				d.byName[alias] = a.IATA
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return dataset{}, err
+	}
+
+	return d, nil
+}
+
+// Lookup finds an airline by its IATA or ICAO code.
+func Lookup(code string) (Airline, bool) {
+	a, ok := data.Get().byCode[strings.ToUpper(strings.TrimSpace(code))]
+	return a, ok
+}
+
+// ByName finds an airline by its name or a known alias, ignoring case, spaces
+// and punctuation.
+func ByName(name string) (Airline, bool) {
+	d := data.Get()
+	code, ok := d.byName[normalise(name)]
+	if !ok {
+		return Airline{}, false
+	}
+	return d.byCode[code], true
+}
+
+// Normalise finds the airline a provider means by its code and name, trying
+// the code first as names vary between providers.
+func Normalise(code, name string) (Airline, bool) {
+	if a, ok := Lookup(code); ok {
+		return a, true
+	}
+	return ByName(name)
+}
+
+// In returns the airlines in an alliance, by IATA code.
+func In(alliance Alliance) []Airline {
+	in := make([]Airline, 0)
+	for code, a := range data.Get().byCode {
+		if code == a.IATA && a.Alliance == alliance {
+			in = append(in, a)
+		}
+	}
+
+	sort.Slice(in, func(i, j int) bool {
+		return in[i].IATA < in[j].IATA
+	})
+	return in
+}
+
+func normalise(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
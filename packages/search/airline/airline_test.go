@@ -0,0 +1,97 @@
+package airline
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+)
+
+func TestLookup(t *testing.T) {
+	for _, code := range []string{"BA", "baw", " BAW "} {
+		a, ok := Lookup(code)
+		if !ok || a.Name != "British Airways" || a.Alliance != Oneworld {
+			t.Errorf("%q: expected British Airways in oneworld, got %+v", code, a)
+		}
+	}
+
+	if a, ok := Lookup("FR"); !ok || !a.LowCost {
+		t.Errorf("expected Ryanair to be low cost, got %+v", a)
+	}
+	if _, ok := Lookup("XX"); ok {
+		t.Error("expected XX not to be found")
+	}
+}
+
+func TestNormalise(t *testing.T) {
+	tests := []struct {
+		code, name string
+		want       string
+	}{
+		{"", "Swiss International Air Lines", "LX"},
+		{"", "SWISS", "LX"},
+		{"", "jet2.com", "LS"},
+		{"", "All Nippon Airways", "NH"},
+		{"KL", "Royal Dutch", "KL"},
+		{"", "Unknown Air", ""},
+	}
+
+	for _, tt := range tests {
+		a, _ := Normalise(tt.code, tt.name)
+		if a.IATA != tt.want {
+			t.Errorf("%q %q: expected %q, got %q", tt.code, tt.name, tt.want, a.IATA)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	defer Load(strings.NewReader(airlinesCSV))
+
+	err := Load(strings.NewReader("iata,icao,name,aliases,alliance,low_cost\nZZ,ZZZ,Zed,,star-alliance,0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Lookup("BA"); ok {
+		t.Error("expected the embedded dataset to be replaced")
+	}
+	if in := In(StarAlliance); len(in) != 1 || in[0].IATA != "ZZ" {
+		t.Errorf("expected only ZZ in star alliance, got %+v", in)
+	}
+
+	if err := Load(strings.NewReader("iata,icao,name,aliases,alliance,low_cost\nZZ,ZZZ,Zed,,one world,0\n")); err == nil {
+		t.Error("expected an error for an unknown alliance")
+	}
+}
+
+func result(codes ...string) search.Result {
+	flights := make([]leg.Flight, len(codes))
+	for i, code := range codes {
+		flights[i] = leg.Flight{AirlineCode: code}
+	}
+	return search.Result{Itineries: []itinery.Itinery{{Outbound: leg.Leg{Flights: flights}}}}
+}
+
+func TestFilter(t *testing.T) {
+	results := []search.Result{
+		result("BA", "QR"),
+		result("BA", "EK"),
+		result("LH", "QR"),
+		result("XX"),
+	}
+
+	kept := Filter(results, Oneworld)
+	if len(kept) != 1 {
+		t.Fatalf("expected only the BA and QR result, got %d", len(kept))
+	}
+
+	kept = Filter(results, Oneworld, StarAlliance)
+	if len(kept) != 2 {
+		t.Errorf("expected 2 results within oneworld or star alliance, got %d", len(kept))
+	}
+
+	if share := Share(results[1], Oneworld); share != 0.5 {
+		t.Errorf("expected half of BA and EK in oneworld, got %v", share)
+	}
+}
@@ -0,0 +1,53 @@
+package airline
+
+import (
+	"slices"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+)
+
+// Of finds the airline flying f, which for codeshares isn't the one selling
+// it.
+func Of(f leg.Flight) (Airline, bool) {
+	name, code := f.Operator()
+	return Normalise(code, name)
+}
+
+// Share is the fraction of r's flights flown by airlines in alliance.
+func Share(r search.Result, alliance Alliance) float64 {
+	flights := r.Flights()
+	if len(flights) == 0 {
+		return 0
+	}
+
+	n := 0
+	for _, f := range flights {
+		if a, ok := Of(f); ok && a.Alliance == alliance {
+			n++
+		}
+	}
+	return float64(n) / float64(len(flights))
+}
+
+// Filter keeps the results flown entirely by airlines in the given alliances.
+// Flights on airlines missing from the dataset don't count as being in one.
+func Filter(results []search.Result, alliances ...Alliance) []search.Result {
+	kept := make([]search.Result, 0, len(results))
+	for _, r := range results {
+		if within(r, alliances) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+func within(r search.Result, alliances []Alliance) bool {
+	for _, f := range r.Flights() {
+		a, ok := Of(f)
+		if !ok || a.Alliance == "" || !slices.Contains(alliances, a.Alliance) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,161 @@
+package airport
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/internal/csvdata"
+)
+
+type Region string
+
+const (
+	Europe         Region = "europe"
+	NorthAmerica   Region = "north-america"
+	CentralAmerica Region = "central-america"
+	SouthAmerica   Region = "south-america"
+	Africa         Region = "africa"
+	MiddleEast     Region = "middle-east"
+	Asia           Region = "asia"
+	Oceania        Region = "oceania"
+)
+
+const (
+	earthRadiusKm    = 6371.0
+	cruiseSpeedKmh   = 800.0
+	taxiAndClimbTime = 30 * time.Minute
+)
+
+type Airport struct {
+	Code    string
+	Name    string
+	City    string
+	Country string // ISO 3166-1 alpha-2
+	Region  Region
+	Lat     float64
+	Lon     float64
+	// typical time to get from the airport to the city centre.
+	CityTransit time.Duration
+}
+
+//go:embed airports.csv
+var airportsCSV string
+
+var airports = csvdata.Embed("airport", airportsCSV, parse)
+
+// Load replaces the airport dataset with the one read from r, in the same csv
+// format as the embedded airports.csv.
+func Load(r io.Reader) error {
+	return airports.Load(r)
+}
+
+func parse(r io.Reader) (map[string]Airport, error) {
+	a := make(map[string]Airport)
+	err := csvdata.Rows(r, 8, func(line int, rec []string) error {
+		lat, err := strconv.ParseFloat(rec[5], 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid latitude: %w", line, err)
+		}
+		lon, err := strconv.ParseFloat(rec[6], 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid longitude: %w", line, err)
+		}
+		transit, err := strconv.Atoi(rec[7])
+		if err != nil {
+			return fmt.Errorf("line %d: invalid city transit time: %w", line, err)
+		}
+
+		a[rec[0]] = Airport{
+			Code:    rec[0],
+			Name:    rec[1],
+			City:    rec[2],
+			Country: rec[3],
+			Region:  Region(rec[4]),
+			Lat:     lat,
+			Lon:     lon,
+
+			CityTransit: time.Duration(transit) * time.Minute,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func Lookup(code string) (Airport, bool) {
+	a, ok := airports.Get()[strings.ToUpper(code)]
+	return a, ok
+}
+
+// InCity returns the airports serving a city, matched case-insensitively.
+func InCity(city string) []Airport {
+	in := make([]Airport, 0)
+	for _, a := range airports.Get() {
+		if strings.EqualFold(a.City, city) {
+			in = append(in, a)
+		}
+	}
+
+	sort.Slice(in, func(i, j int) bool {
+		return in[i].Code < in[j].Code
+	})
+	return in
+}
+
+func All() []Airport {
+	as := airports.Get()
+	all := make([]Airport, 0, len(as))
+	for _, a := range as {
+		all = append(all, a)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Code < all[j].Code
+	})
+	return all
+}
+
+// Distance returns the great-circle distance between two airports in kilometres.
+func Distance(from, to string) (float64, bool) {
+	a, ok := Lookup(from)
+	if !ok {
+		return 0, false
+	}
+	b, ok := Lookup(to)
+	if !ok {
+		return 0, false
+	}
+
+	return haversine(a.Lat, a.Lon, b.Lat, b.Lon), true
+}
+
+// EstimateDuration gives a rough block time between two airports for when the
+// provider doesn't tell us how long the flight is.
+func EstimateDuration(from, to string) (time.Duration, bool) {
+	km, ok := Distance(from, to)
+	if !ok {
+		return 0, false
+	}
+
+	return time.Duration(km/cruiseSpeedKmh*float64(time.Hour)) + taxiAndClimbTime, true
+}
+
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
@@ -0,0 +1,54 @@
+package airport
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	a, ok := Lookup("lhr")
+	if !ok {
+		t.Fatal("expected LHR to be found")
+	}
+	if a.City != "London" || a.Country != "GB" || a.Region != Europe {
+		t.Errorf("unexpected airport %+v", a)
+	}
+
+	if _, ok := Lookup("XXX"); ok {
+		t.Error("expected unknown airport to not be found")
+	}
+}
+
+func TestDistance(t *testing.T) {
+	km, ok := Distance("LHR", "JFK")
+	if !ok {
+		t.Fatal("expected distance to be found")
+	}
+	if km < 5500 || km > 5600 {
+		t.Errorf("expected LHR-JFK to be ~5550km, got %f", km)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	defer func() {
+		if err := Load(strings.NewReader(airportsCSV)); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	err := Load(strings.NewReader("code,name,city,country,region,lat,lon,city_minutes\nAAA,Test,Test City,XX,europe,1,2,30\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := Lookup("LHR"); ok {
+		t.Error("expected LHR to be replaced")
+	}
+	if _, ok := Lookup("AAA"); !ok {
+		t.Error("expected AAA to be loaded")
+	}
+
+	if err := Load(strings.NewReader("code,name\nAAA,Test\n")); err == nil {
+		t.Error("expected error for malformed dataset")
+	}
+}
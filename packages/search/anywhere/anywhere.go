@@ -0,0 +1,203 @@
+package anywhere
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"github.com/tobyrushton/flyvia/packages/search/provider"
+	"github.com/tobyrushton/flyvia/packages/search/via"
+)
+
+type Options struct {
+	// hubs to connect through, when empty the cheapest explored destinations
+	// from the origin are used.
+	Hubs []string
+	// how many of the cheapest explored destinations to use as hubs, zero for
+	// defaultMaxHubs.
+	MaxHubs int
+
+	// maximum number of provider calls across every explore and search, zero
+	// for no limit.
+	MaxCalls int
+
+	MinLayover time.Duration
+	MaxLayover time.Duration
+}
+
+const defaultMaxHubs = 5
+
+type Destination struct {
+	Airport string
+	City    string
+	Country string
+	Hub     string
+
+	// what explore prices suggested the trip would cost before searching.
+	Estimate money.Money
+	Best     search.Result
+}
+
+type candidate struct {
+	hub      string
+	dest     itinery.ExploreItinery
+	estimate money.Money
+}
+
+// Search finds the cheapest split-ticket trip to any destination reachable
+// through a hub, using only the origin and dates of req.
+func Search(
+	ctx context.Context,
+	p provider.Provider,
+	req provider.Request,
+	opts Options,
+) ([]Destination, error) {
+	b := newBudgeted(p, opts.MaxCalls)
+
+	explore := provider.ExploreRequest{
+		Origins:       []string{req.Origin},
+		DepartureFrom: req.DepartureDate,
+		Party:         req.Party,
+		Currency:      req.Currency,
+		Class:         req.Class,
+	}
+	if !req.ReturnDate.IsZero() {
+		explore.MinTripLength = req.TripLength()
+		explore.MaxTripLength = req.TripLength()
+	}
+
+	fromOrigin, err := b.Explore(ctx, explore)
+	if err != nil {
+		return nil, err
+	}
+
+	toHub := make(map[string]money.Money, len(fromOrigin))
+	for _, ei := range fromOrigin {
+		toHub[ei.Destination] = ei.Price
+	}
+
+	hubs := opts.Hubs
+	if len(hubs) == 0 {
+		n := opts.MaxHubs
+		if n <= 0 {
+			n = defaultMaxHubs
+		}
+		if hubs, err = cheapestHubs(fromOrigin, n); err != nil {
+			return nil, err
+		}
+	}
+
+	candidates := make([]candidate, 0)
+	for _, hub := range hubs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		hubPrice, ok := toHub[hub]
+		if !ok {
+			continue
+		}
+
+		hubExplore := explore
+		hubExplore.Origins = []string{hub}
+
+		fromHub, err := b.Explore(ctx, hubExplore)
+		if errors.Is(err, ErrBudgetExhausted) {
+			break
+		}
+		// one hub failing shouldn't lose what the others find.
+		if err != nil {
+			continue
+		}
+
+		for _, ei := range fromHub {
+			if ei.Destination == hub || ei.Reaches(req.Origin) {
+				continue
+			}
+			estimate, err := hubPrice.Add(ei.Price)
+			if err != nil {
+				continue
+			}
+			candidates = append(candidates, candidate{
+				hub:      hub,
+				dest:     ei,
+				estimate: estimate,
+			})
+		}
+	}
+
+	if err := money.Sort(candidates, func(c candidate) money.Money { return c.estimate }); err != nil {
+		return nil, err
+	}
+
+	s := via.New(b, opts.MinLayover, opts.MaxLayover)
+	best := make(map[string]*Destination)
+
+	for _, c := range candidates {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// already found something cheaper than this route is expected to be.
+		if d, ok := best[c.dest.Destination]; ok {
+			if cmp, err := c.estimate.Cmp(d.Best.Price); err != nil || cmp >= 0 {
+				continue
+			}
+		}
+
+		destReq := req
+		destReq.Destination = c.dest.Destination
+
+		results, err := s.Search(ctx, destReq, c.hub)
+		if errors.Is(err, ErrBudgetExhausted) {
+			break
+		}
+		if err != nil {
+			continue
+		}
+
+		for _, r := range results {
+			if d, ok := best[c.dest.Destination]; ok {
+				if cmp, err := r.Price.Cmp(d.Best.Price); err != nil || cmp >= 0 {
+					continue
+				}
+			}
+			best[c.dest.Destination] = &Destination{
+				Airport:  c.dest.Destination,
+				City:     c.dest.City,
+				Country:  c.dest.Country,
+				Hub:      c.hub,
+				Estimate: c.estimate,
+				Best:     r,
+			}
+		}
+	}
+
+	destinations := make([]Destination, 0, len(best))
+	for _, d := range best {
+		destinations = append(destinations, *d)
+	}
+
+	if err := money.Sort(destinations, func(d Destination) money.Money { return d.Best.Price }); err != nil {
+		return nil, err
+	}
+
+	return destinations, nil
+}
+
+func cheapestHubs(eis []itinery.ExploreItinery, n int) ([]string, error) {
+	sorted := slices.Clone(eis)
+	if err := money.Sort(sorted, func(ei itinery.ExploreItinery) money.Money { return ei.Price }); err != nil {
+		return nil, err
+	}
+
+	hubs := make([]string, 0, n)
+	for i := 0; i < n && i < len(sorted); i++ {
+		hubs = append(hubs, sorted[i].Destination)
+	}
+	return hubs, nil
+}
@@ -0,0 +1,259 @@
+package anywhere
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money/moneytest"
+	"github.com/tobyrushton/flyvia/packages/search/provider"
+	"github.com/tobyrushton/flyvia/packages/search/provider/providertest"
+)
+
+var baseTime = time.Date(2030, 1, 1, 8, 0, 0, 0, time.UTC)
+
+type fakeRoutes struct {
+	explores map[string][]itinery.ExploreItinery
+	prices   map[[2]string]float64
+	// origins whose explore fails.
+	failing map[string]bool
+}
+
+func (f *fakeRoutes) explore(
+	_ context.Context,
+	req provider.ExploreRequest,
+) ([]itinery.ExploreItinery, error) {
+	if f.failing[req.Origins[0]] {
+		return nil, errors.New("explore failed")
+	}
+	return f.explores[req.Origins[0]], nil
+}
+
+// search returns a single ticket, tickets from the origin arrive at 10:00 and
+// onward tickets leave at 12:00 so every pair connects.
+func (f *fakeRoutes) search(
+	_ context.Context,
+	req provider.Request,
+) ([]itinery.Itinery, error) {
+	price, ok := f.prices[[2]string{req.Origin, req.Destination}]
+	if !ok {
+		return []itinery.Itinery{}, nil
+	}
+
+	out, in := baseTime, baseTime.Add(7*24*time.Hour)
+	if req.Origin != "London" {
+		out, in = out.Add(4*time.Hour), in.Add(-4*time.Hour)
+	}
+
+	return []itinery.Itinery{{
+		Outbound: leg.Leg{
+			DepartureAirport: req.Origin,
+			ArrivalAirport:   req.Destination,
+			DepartureTime:    out,
+			ArrivalTime:      out.Add(2 * time.Hour),
+		},
+		Inbound: leg.Leg{
+			DepartureAirport: req.Destination,
+			ArrivalAirport:   req.Origin,
+			DepartureTime:    in,
+			ArrivalTime:      in.Add(2 * time.Hour),
+		},
+		Price: moneytest.GBP(price),
+	}}, nil
+}
+
+// newFakeProvider explores and searches fakeRoutes, with explores from the
+// failing origins failing.
+func newFakeProvider(failing ...string) *providertest.Provider {
+	f := &fakeRoutes{
+		explores: map[string][]itinery.ExploreItinery{
+			"London": {
+				{Destination: "DUB", Price: moneytest.GBP(40)},
+				{Destination: "AMS", Price: moneytest.GBP(60)},
+				{Destination: "JFK", Price: moneytest.GBP(500)},
+			},
+			"DUB": {
+				{Destination: "JFK", Price: moneytest.GBP(300), City: "New York"},
+				{Destination: "LHR", Price: moneytest.GBP(40), City: "London"},
+			},
+			"AMS": {
+				{Destination: "JFK", Price: moneytest.GBP(350), City: "New York"},
+				{Destination: "BKK", Price: moneytest.GBP(400), City: "Bangkok"},
+			},
+		},
+		prices: map[[2]string]float64{
+			{"London", "DUB"}: 40,
+			{"London", "AMS"}: 60,
+			{"DUB", "JFK"}:    300,
+			{"AMS", "JFK"}:    350,
+			{"AMS", "BKK"}:    400,
+		},
+		failing: make(map[string]bool),
+	}
+	for _, origin := range failing {
+		f.failing[origin] = true
+	}
+	return &providertest.Provider{ExploreFunc: f.explore, SearchFunc: f.search}
+}
+
+func TestSearch_GroupsByDestination(t *testing.T) {
+	p := newFakeProvider()
+
+	destinations, err := Search(
+		context.Background(),
+		p,
+		provider.Request{
+			Origin:        "London",
+			DepartureDate: baseTime,
+			ReturnDate:    baseTime.Add(7 * 24 * time.Hour),
+			Party:         provider.Party{Adults: 1},
+		},
+		Options{
+			MaxHubs:    2,
+			MaxCalls:   20,
+			MinLayover: time.Hour,
+			MaxLayover: 6 * time.Hour,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(destinations) != 2 {
+		t.Fatalf("expected 2 destinations, got %d", len(destinations))
+	}
+
+	if destinations[0].Airport != "JFK" || destinations[0].Hub != "DUB" {
+		t.Errorf("expected cheapest destination to be JFK via DUB, got %s via %s", destinations[0].Airport, destinations[0].Hub)
+	}
+	if destinations[0].Best.Price != moneytest.GBP(340) {
+		t.Errorf("expected JFK to cost 340, got %s", destinations[0].Best.Price)
+	}
+	if destinations[1].Airport != "BKK" {
+		t.Errorf("expected second destination to be BKK, got %s", destinations[1].Airport)
+	}
+}
+
+func TestSearch_RespectsBudget(t *testing.T) {
+	p := newFakeProvider()
+
+	destinations, err := Search(
+		context.Background(),
+		p,
+		provider.Request{
+			Origin:        "London",
+			DepartureDate: baseTime,
+			ReturnDate:    baseTime.Add(7 * 24 * time.Hour),
+			Party:         provider.Party{Adults: 1},
+		},
+		Options{
+			MaxHubs:    2,
+			MaxCalls:   4,
+			MinLayover: time.Hour,
+			MaxLayover: 6 * time.Hour,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Calls() > 4 {
+		t.Errorf("expected at most 4 provider calls, got %d", p.Calls())
+	}
+	if len(destinations) != 0 {
+		t.Errorf("expected budget to run out before any search completed, got %d destinations", len(destinations))
+	}
+}
+
+func TestSearch_BudgetExhaustedBeforeExplore(t *testing.T) {
+	p := newFakeProvider()
+	// spend the only call.
+	b := newBudgeted(p, 1)
+	if !b.take() {
+		t.Fatal("expected a budget of 1 to allow one call")
+	}
+
+	_, err := b.Explore(context.Background(), provider.ExploreRequest{Origins: []string{"London"}})
+	if err != ErrBudgetExhausted {
+		t.Errorf("expected ErrBudgetExhausted, got %v", err)
+	}
+	if p.Calls() != 0 {
+		t.Errorf("expected no calls to reach the provider, got %d", p.Calls())
+	}
+}
+
+func TestSearch_NoLimits(t *testing.T) {
+	destinations, err := Search(
+		context.Background(),
+		newFakeProvider(),
+		provider.Request{
+			Origin:        "London",
+			DepartureDate: baseTime,
+			ReturnDate:    baseTime.Add(7 * 24 * time.Hour),
+			Party:         provider.Party{Adults: 1},
+		},
+		Options{MinLayover: time.Hour, MaxLayover: 6 * time.Hour},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(destinations) != 2 {
+		t.Errorf("expected 2 destinations with no call limit, got %d", len(destinations))
+	}
+}
+
+func TestSearch_SkipsFailedHub(t *testing.T) {
+	p := newFakeProvider("DUB")
+
+	destinations, err := Search(
+		context.Background(),
+		p,
+		provider.Request{
+			Origin:        "London",
+			DepartureDate: baseTime,
+			ReturnDate:    baseTime.Add(7 * 24 * time.Hour),
+			Party:         provider.Party{Adults: 1},
+		},
+		Options{
+			MaxHubs:    2,
+			MinLayover: time.Hour,
+			MaxLayover: 6 * time.Hour,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(destinations) != 2 {
+		t.Fatalf("expected 2 destinations through AMS, got %d", len(destinations))
+	}
+	for _, d := range destinations {
+		if d.Hub != "AMS" {
+			t.Errorf("expected %s to be reached through AMS, got %s", d.Airport, d.Hub)
+		}
+	}
+}
+
+func TestSearch_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Search(
+		ctx,
+		newFakeProvider(),
+		provider.Request{
+			Origin:        "London",
+			DepartureDate: baseTime,
+			ReturnDate:    baseTime.Add(7 * 24 * time.Hour),
+			Party:         provider.Party{Adults: 1},
+		},
+		Options{MinLayover: time.Hour, MaxLayover: 6 * time.Hour},
+	)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancellation to be returned, got %v", err)
+	}
+}
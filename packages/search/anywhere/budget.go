@@ -0,0 +1,67 @@
+package anywhere
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/provider"
+)
+
+var ErrBudgetExhausted = errors.New("provider call budget exhausted")
+
+// budgeted wraps a provider so every Explore and Search call is taken from a
+// shared budget, cached via searches don't count as they never reach it. A
+// budget of zero or less is unlimited.
+type budgeted struct {
+	p provider.Provider
+
+	mu        sync.Mutex
+	left      int
+	unlimited bool
+}
+
+func newBudgeted(p provider.Provider, calls int) *budgeted {
+	return &budgeted{
+		p:         p,
+		left:      calls,
+		unlimited: calls <= 0,
+	}
+}
+
+func (b *budgeted) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.unlimited {
+		return true
+	}
+	if b.left <= 0 {
+		return false
+	}
+	b.left--
+	return true
+}
+
+func (b *budgeted) Explore(
+	ctx context.Context,
+	req provider.ExploreRequest,
+) ([]itinery.ExploreItinery, error) {
+	if !b.take() {
+		return nil, ErrBudgetExhausted
+	}
+	return b.p.Explore(ctx, req)
+}
+
+func (b *budgeted) Search(
+	ctx context.Context,
+	req provider.Request,
+) ([]itinery.Itinery, error) {
+	if !b.take() {
+		return nil, ErrBudgetExhausted
+	}
+	return b.p.Search(ctx, req)
+}
+
+var _ provider.Provider = (*budgeted)(nil)
@@ -0,0 +1,211 @@
+package baggage
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/fx"
+	"github.com/tobyrushton/flyvia/packages/search/internal/csvdata"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"golang.org/x/text/currency"
+)
+
+// the row used for airlines missing from the table.
+const defaultAirline = "*"
+
+// Bags is what each passenger is bringing.
+type Bags struct {
+	CarryOn int
+	Checked int
+}
+
+func (b Bags) IsZero() bool {
+	return b.CarryOn == 0 && b.Checked == 0
+}
+
+// Policy is what an airline typically includes and charges per passenger for
+// each direction flown. Bags over the allowance cost the fee each. A ticket's
+// own conditions decide what it includes, the allowance here is only what
+// providers without fare rules estimate them from.
+type Policy struct {
+	Airline         string
+	Name            string
+	CarryOnIncluded int
+	CarryOnFee      money.Money
+	CheckedIncluded int
+	CheckedFee      money.Money
+}
+
+//go:embed fees.csv
+var feesCSV string
+
+var policies = csvdata.Embed("baggage", feesCSV, parse)
+
+// Load replaces the fee table with the one read from r, in the same csv
+// format as the embedded fees.csv. It must include a * row for airlines not
+// in the table.
+func Load(r io.Reader) error {
+	return policies.Load(r)
+}
+
+func parse(r io.Reader) (map[string]Policy, error) {
+	p := make(map[string]Policy)
+	err := csvdata.Rows(r, 7, func(line int, rec []string) error {
+		cur, err := currency.ParseISO(rec[6])
+		if err != nil {
+			return fmt.Errorf("line %d: invalid currency: %w", line, err)
+		}
+
+		nums := make([]float64, 4)
+		for j := range nums {
+			if nums[j], err = strconv.ParseFloat(rec[2+j], 64); err != nil {
+				return fmt.Errorf("line %d: invalid number: %w", line, err)
+			}
+		}
+
+		p[rec[0]] = Policy{
+			Airline:         rec[0],
+			Name:            rec[1],
+			CarryOnIncluded: int(nums[0]),
+			CarryOnFee:      money.New(nums[1], cur),
+			CheckedIncluded: int(nums[2]),
+			CheckedFee:      money.New(nums[3], cur),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p[defaultAirline]; !ok {
+		return nil, fmt.Errorf("missing the %s row for unknown airlines", defaultAirline)
+	}
+
+	return p, nil
+}
+
+// For returns the policy of an airline by IATA code, or the default policy
+// when it isn't known.
+func For(airline string) Policy {
+	p := policies.Get()
+	if policy, ok := p[strings.ToUpper(airline)]; ok {
+		return policy
+	}
+	return p[defaultAirline]
+}
+
+// Typical is the conditions a standard fare on the airline comes with, for
+// providers that don't give fare rules.
+func (p Policy) Typical() itinery.Conditions {
+	return itinery.Conditions{
+		CarryOn:     p.CarryOnIncluded > 0,
+		CheckedBags: p.CheckedIncluded,
+		Estimated:   true,
+	}
+}
+
+// included is p with the allowance c gives instead of the typical one. Rules
+// that are missing or only estimated keep p's own allowance.
+func (p Policy) included(c itinery.Conditions) Policy {
+	if c == (itinery.Conditions{}) || c.Estimated {
+		return p
+	}
+	p.CarryOnIncluded = 0
+	if c.CarryOn {
+		p.CarryOnIncluded = 1
+	}
+	p.CheckedIncluded = c.CheckedBags
+	return p
+}
+
+// Fee is what b costs one passenger for one direction.
+func (p Policy) Fee(b Bags) money.Money {
+	fee := p.CarryOnFee.Mul(float64(max(0, b.CarryOn-p.CarryOnIncluded)))
+	// both fees come from the same row so are in the same currency.
+	fee, _ = fee.Add(p.CheckedFee.Mul(float64(max(0, b.Checked-p.CheckedIncluded))))
+	return fee
+}
+
+// Apply returns r with what everyone's bags cost across every ticket in
+// BagFees, charged per direction by the airline flying the first flight for
+// the bags over what the ticket's conditions include.
+// Fees in another currency to r are converted with conv. Without a converter,
+// or when the conversion fails, the fee is left out of BagFees and a warning
+// added instead.
+func Apply(
+	ctx context.Context,
+	r search.Result,
+	bags Bags,
+	passengers int,
+	conv *fx.Converter,
+) (search.Result, error) {
+	cur := r.Price.Currency
+	total := money.Zero(cur)
+	// one warning per currency, not per leg.
+	unconverted := make(map[currency.Unit]bool)
+
+	for _, itin := range r.Itineries {
+		for _, l := range legs(itin) {
+			fee := For(l.Flights[0].AirlineCode).included(itin.Conditions).Fee(bags).Mul(float64(passengers))
+			if fee.IsZero() {
+				continue
+			}
+
+			if fee.Currency != cur {
+				converted, err := convert(ctx, conv, fee, cur)
+				if err != nil {
+					unconverted[fee.Currency] = true
+					continue
+				}
+				fee = converted
+			}
+
+			var err error
+			if total, err = total.Add(fee); err != nil {
+				return search.Result{}, err
+			}
+		}
+	}
+
+	if len(unconverted) > 0 {
+		r.Warnings = slices.Clone(r.Warnings)
+		for _, c := range slices.SortedFunc(maps.Keys(unconverted), func(a, b currency.Unit) int {
+			return strings.Compare(a.String(), b.String())
+		}) {
+			r.Warnings = append(r.Warnings, fmt.Sprintf("bag fee in %s not converted, left out of the total", c))
+		}
+	}
+
+	r.BagFees = total
+	// separate tickets don't check bags through, they're collected and
+	// checked in again between each.
+	r.RecollectBags = bags.Checked > 0 && len(r.Itineries) > 1
+
+	return r, nil
+}
+
+func convert(ctx context.Context, conv *fx.Converter, fee money.Money, to currency.Unit) (money.Money, error) {
+	if conv == nil {
+		return money.Money{}, fmt.Errorf("%w: bag fees in %s", money.ErrCurrencyMismatch, fee.Currency)
+	}
+	return conv.Convert(ctx, fee, to, time.Now())
+}
+
+func legs(itin itinery.Itinery) []leg.Leg {
+	legs := make([]leg.Leg, 0, 2)
+	for _, l := range []leg.Leg{itin.Outbound, itin.Inbound} {
+		if len(l.Flights) > 0 {
+			legs = append(legs, l)
+		}
+	}
+	return legs
+}
@@ -0,0 +1,138 @@
+package baggage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/fx"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"github.com/tobyrushton/flyvia/packages/search/money/moneytest"
+	"golang.org/x/text/currency"
+)
+
+func ticket(airline string, price float64) itinery.Itinery {
+	return itinery.Itinery{
+		Outbound:   leg.Leg{Flights: []leg.Flight{{AirlineCode: airline}}},
+		Price:      moneytest.GBP(price),
+		Conditions: For(airline).Typical(),
+	}
+}
+
+func TestPolicyFee(t *testing.T) {
+	bags := Bags{CarryOn: 1, Checked: 1}
+
+	if fee := For("FR").Fee(bags); fee != money.New(55, currency.EUR) {
+		t.Errorf("expected Ryanair to charge EUR 55, got %s", fee)
+	}
+	if fee := For("ba").Fee(bags); !fee.IsZero() {
+		t.Errorf("expected British Airways to include both bags, got %s", fee)
+	}
+	if p := For("ZZ"); p.Airline != defaultAirline {
+		t.Errorf("expected unknown airlines to use the default policy, got %s", p.Airline)
+	}
+}
+
+func TestApply(t *testing.T) {
+	r, err := search.NewResult(ticket("U2", 40), ticket("BA", 300))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err = Apply(context.Background(), r, Bags{Checked: 1}, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.BagFees != moneytest.GBP(60) {
+		t.Errorf("expected easyJet to charge 2 passengers 60, got %s", r.BagFees)
+	}
+	if total, _ := r.Total(); total != moneytest.GBP(400) {
+		t.Errorf("expected a total of 400 with bags, got %s", total)
+	}
+	if !r.RecollectBags {
+		t.Error("expected checked bags to be recollected between tickets")
+	}
+}
+
+func TestApply_TicketConditions(t *testing.T) {
+	// a light fare on an airline that usually includes a checked bag.
+	light := ticket("BA", 300)
+	light.Conditions = itinery.Conditions{CarryOn: true}
+
+	r, err := search.NewResult(ticket("FR", 40), light)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ryanair's EUR fees can't be converted, leaving British Airways'.
+	r, err = Apply(context.Background(), r, Bags{CarryOn: 1, Checked: 1}, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.BagFees != moneytest.GBP(65) {
+		t.Errorf("expected the checked bag the fare leaves out to cost 65, got %s", r.BagFees)
+	}
+
+	// fares without rules, or with only estimated ones, keep the airline's.
+	for _, c := range []itinery.Conditions{{}, {Estimated: true}} {
+		unknown := ticket("BA", 300)
+		unknown.Conditions = c
+		r, err := search.NewResult(unknown, ticket("BA", 300))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err = Apply(context.Background(), r, Bags{CarryOn: 1, Checked: 1}, 1, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !r.BagFees.IsZero() {
+			t.Errorf("expected British Airways' bags to be included with %+v, got %s", c, r.BagFees)
+		}
+	}
+}
+
+func TestApply_Converts(t *testing.T) {
+	r, err := search.NewResult(ticket("FR", 40), ticket("BA", 300))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unconverted, err := Apply(context.Background(), r, Bags{Checked: 1}, 1, nil)
+	if err != nil {
+		t.Fatalf("expected a fee that can't be converted to be skipped, got %v", err)
+	}
+	if !unconverted.BagFees.IsZero() || len(unconverted.Warnings) != 1 {
+		t.Errorf("expected the EUR fee to be left out with a warning, got %s and %v", unconverted.BagFees, unconverted.Warnings)
+	}
+
+	rates := fx.NewStatic()
+	rates.Set(currency.EUR, currency.GBP, time.Time{}, 0.8)
+
+	r, err = Apply(context.Background(), r, Bags{Checked: 1}, 1, fx.NewConverter(rates))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.BagFees != moneytest.GBP(28) {
+		t.Errorf("expected EUR 35 to convert to GBP 28, got %s", r.BagFees)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	defer Load(strings.NewReader(feesCSV))
+
+	if err := Load(strings.NewReader("airline,name,carry_on_included,carry_on_fee,checked_included,checked_fee,currency\nFR,Ryanair,0,20,0,35,EUR\n")); err == nil {
+		t.Error("expected an error without a default row")
+	}
+
+	err := Load(strings.NewReader("airline,name,carry_on_included,carry_on_fee,checked_included,checked_fee,currency\n*,Unknown,1,0,1,0,GBP\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee := For("FR").Fee(Bags{Checked: 1}); !fee.IsZero() {
+		t.Errorf("expected the overriding table to be used, got %s", fee)
+	}
+}
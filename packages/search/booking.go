@@ -0,0 +1,100 @@
+package search
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+)
+
+// Booking is one ticket to buy.
+type Booking struct {
+	// index into the result's itineries.
+	Ticket      int
+	Origin      string
+	Destination string
+	Departure   time.Time
+	BookingURL  string
+	Price       money.Money
+	// who the ticket is for, with what each type of passenger pays.
+	Passengers []itinery.Fare
+}
+
+// BookingPlan is the order to buy a result's tickets in.
+type BookingPlan struct {
+	Bookings []Booking
+	// what the tickets cost, without bags or hotels, see Result.Total.
+	Tickets money.Money
+}
+
+// BookingPlan orders r's tickets so the riskiest, the one most likely to be
+// gone when it comes to buying it, is bought first, so if it is nothing has
+// been spent:
+//
+//   - non-flexible fares before flexible ones, as they're the cheapest
+//     buckets and sell out first.
+//   - tickets leaving from a connection the result may miss before the rest,
+//     as they're what a missed connection puts at risk.
+//   - for results not verified, the tickets whose price was seen longest ago
+//     first, as they're the least sure to still be sold.
+//   - then the earliest to leave, with the dearest first between tickets
+//     leaving together.
+func (r Result) BookingPlan() BookingPlan {
+	plan := BookingPlan{
+		Bookings: make([]Booking, len(r.Itineries)),
+		Tickets:  r.Price,
+	}
+
+	flexible := make([]bool, len(r.Itineries))
+	missable := make([]bool, len(r.Itineries))
+	seen := make([]time.Time, len(r.Itineries))
+	for i, itin := range r.Itineries {
+		plan.Bookings[i] = Booking{
+			Ticket:      i,
+			Origin:      itin.Outbound.DepartureAirport,
+			Destination: itin.Outbound.ArrivalAirport,
+			Departure:   itin.Outbound.DepartureTime,
+			BookingURL:  itin.BookingURL,
+			Price:       itin.Price,
+			Passengers:  itin.Breakdown.Fares,
+		}
+		flexible[i] = itin.Conditions.Flexible()
+		missable[i] = r.missable(itin)
+		// verified tickets were all seen at once.
+		if !r.Verified {
+			seen[i] = itin.ObservedAt.Truncate(time.Minute)
+		}
+	}
+
+	sort.SliceStable(plan.Bookings, func(i, j int) bool {
+		a, b := plan.Bookings[i].Ticket, plan.Bookings[j].Ticket
+		switch {
+		case flexible[a] != flexible[b]:
+			return !flexible[a]
+		case missable[a] != missable[b]:
+			return missable[a]
+		case !seen[a].Equal(seen[b]):
+			return seen[a].Before(seen[b])
+		case !plan.Bookings[i].Departure.Equal(plan.Bookings[j].Departure):
+			return plan.Bookings[i].Departure.Before(plan.Bookings[j].Departure)
+		}
+		c, err := plan.Bookings[i].Price.Cmp(plan.Bookings[j].Price)
+		return err == nil && c > 0
+	})
+
+	return plan
+}
+
+// missable reports whether itin leaves from a tight connection r may miss.
+func (r Result) missable(itin itinery.Itinery) bool {
+	if r.MissProbability == 0 {
+		return false
+	}
+	for _, c := range r.Connections {
+		if c.Kind == Tight && c.Departure.Equal(itin.Outbound.DepartureTime) {
+			return true
+		}
+	}
+	return false
+}
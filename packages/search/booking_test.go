@@ -0,0 +1,71 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"golang.org/x/text/currency"
+)
+
+func TestBookingPlan(t *testing.T) {
+	positioning := oneWay("MAN", "LHR", day.Add(7*time.Hour))
+	positioning.Conditions = itinery.Conditions{Changeable: true}
+	longHaul := oneWay("LHR", "BKK", day.Add(12*time.Hour))
+	longHaul.Price = money.New(450, currency.GBP)
+	longHaul.BookingURL = "https://example.com/bkk"
+	longHaul.Breakdown = itinery.Split(longHaul.Price, map[itinery.PassengerType]int{itinery.Adult: 2})
+	onward := oneWay("BKK", "HKT", day.Add(36*time.Hour))
+
+	r, err := NewChain(positioning, longHaul, onward)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan := r.BookingPlan()
+	if len(plan.Bookings) != 3 {
+		t.Fatalf("expected a booking per ticket, got %d", len(plan.Bookings))
+	}
+
+	first := plan.Bookings[0]
+	if first.Ticket != 1 || first.BookingURL != "https://example.com/bkk" || len(first.Passengers) != 1 || first.Passengers[0].Count != 2 {
+		t.Errorf("expected the long-haul for 2 adults to be booked first, got %+v", first)
+	}
+	if plan.Bookings[1].Origin != "BKK" || plan.Bookings[2].Origin != "MAN" {
+		t.Errorf("expected the non-flexible onward ticket before the changeable one, got %s then %s", plan.Bookings[1].Origin, plan.Bookings[2].Origin)
+	}
+	if plan.Tickets != money.New(650, currency.GBP) {
+		t.Errorf("expected the tickets to total 650, got %s", plan.Tickets)
+	}
+}
+
+func TestBookingPlan_Availability(t *testing.T) {
+	seen := day.Add(-time.Hour)
+	first := oneWay("LHR", "AMS", day.Add(8*time.Hour))
+	first.ObservedAt = seen
+	onward := oneWay("AMS", "JFK", day.Add(12*time.Hour))
+	onward.ObservedAt = seen.Add(-30 * time.Minute)
+
+	r, err := NewChain(first, onward)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plan := r.BookingPlan(); plan.Bookings[0].Origin != "AMS" {
+		t.Errorf("expected the ticket priced longest ago first, got %s", plan.Bookings[0].Origin)
+	}
+
+	// verified, so only the risk puts the later ticket first.
+	r = Classify(r, money.Money{})
+	r.MissProbability = 0.2
+	r.Verified = true
+	if plan := r.BookingPlan(); plan.Bookings[0].Origin != "AMS" {
+		t.Errorf("expected the ticket after a tight connection first, got %s", plan.Bookings[0].Origin)
+	}
+
+	r.MissProbability = 0
+	if plan := r.BookingPlan(); plan.Bookings[0].Origin != "LHR" {
+		t.Errorf("expected verified tickets in the order they leave, got %s first", plan.Bookings[0].Origin)
+	}
+}
@@ -0,0 +1,360 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"github.com/tobyrushton/flyvia/packages/search/provider"
+	"golang.org/x/text/currency"
+)
+
+const (
+	defaultConcurrency = 4
+	// enough to search every day of a one-way month.
+	defaultMaxSearches = 31
+)
+
+type Day struct {
+	Date time.Time `json:"date"`
+	// zero when no fare was found for the day.
+	Price money.Money `json:"price,omitzero"`
+	// the other end of the cheapest trip, the return date for departure days
+	// and the departure date for return days. Zero for one-way calendars.
+	Paired time.Time `json:"paired,omitzero"`
+}
+
+type Calendar struct {
+	Origin      string        `json:"origin"`
+	Destination string        `json:"destination"`
+	Year        int           `json:"year"`
+	Month       time.Month    `json:"month"`
+	Currency    currency.Unit `json:"-"`
+
+	Departures []Day `json:"departures"`
+	Returns    []Day `json:"returns,omitempty"`
+}
+
+// Builder builds fare calendars, caching every provider call so calendars for
+// other destinations from the same origin mostly come from the cache. When the
+// provider is a provider.DatePricer the whole calendar is priced in one call,
+// with explore and search only filling the days it has no fare for.
+type Builder struct {
+	p provider.Provider
+
+	// number of provider calls run at once.
+	Concurrency int
+	// most searches a calendar can run for days explore has no fare for, each
+	// is a full search, zero to only use explore.
+	MaxSearches int
+
+	mu       sync.Mutex
+	explores map[string][]itinery.ExploreItinery
+	searches map[string]money.Money
+	dates    map[string][]provider.DatePrice
+}
+
+func NewBuilder(p provider.Provider) *Builder {
+	return &Builder{
+		p:           p,
+		Concurrency: defaultConcurrency,
+		MaxSearches: defaultMaxSearches,
+		explores:    make(map[string][]itinery.ExploreItinery),
+		searches:    make(map[string]money.Money),
+		dates:       make(map[string][]provider.DatePrice),
+	}
+}
+
+type trip struct {
+	departure time.Time
+	ret       time.Time
+}
+
+func (t trip) key() string {
+	return t.departure.Format(time.DateOnly) + "|" + t.ret.Format(time.DateOnly)
+}
+
+// Build returns the cheapest fare for every departure day in the month, and
+// for round trips the cheapest fare for every return day too. Trip lengths are
+// in days, both zero builds a one-way calendar. Days whose fare couldn't be
+// found are left empty, an error is only returned when none could be.
+func (b *Builder) Build(
+	ctx context.Context,
+	req provider.Request,
+	year int,
+	month time.Month,
+	minTripLength, maxTripLength int,
+) (*Calendar, error) {
+	if maxTripLength < minTripLength {
+		return nil, fmt.Errorf("invalid trip length range %d-%d", minTripLength, maxTripLength)
+	}
+
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	// trips leaving in the previous month can still return in this one.
+	trips := make([]trip, 0)
+	for d := first.AddDate(0, 0, -maxTripLength); !d.After(last); d = d.AddDate(0, 0, 1) {
+		if d.Before(today) {
+			continue
+		}
+		if maxTripLength == 0 {
+			trips = append(trips, trip{departure: d})
+			continue
+		}
+		for l := minTripLength; l <= maxTripLength; l++ {
+			ret := d.AddDate(0, 0, l)
+			if ret.Before(first) {
+				continue
+			}
+			trips = append(trips, trip{departure: d, ret: ret})
+		}
+	}
+
+	prices := make([]money.Money, len(trips))
+	errs := make([]error, len(trips))
+	priced := b.priceDates(ctx, req, trips, minTripLength, maxTripLength)
+
+	sem := make(chan struct{}, max(1, b.Concurrency))
+	wg := sync.WaitGroup{}
+	searches := &atomic.Int32{}
+
+	for i, t := range trips {
+		if price, ok := priced[t.key()]; ok {
+			prices[i] = price
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, t trip) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			dayReq := req
+			dayReq.DepartureDate = t.departure
+			dayReq.ReturnDate = t.ret
+			prices[i], errs[i] = b.fare(ctx, dayReq, searches)
+		}(i, t)
+	}
+
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	if len(trips) > 0 && failed == len(trips) {
+		return nil, errs[0]
+	}
+
+	c := &Calendar{
+		Origin:      req.Origin,
+		Destination: req.Destination,
+		Year:        year,
+		Month:       month,
+		Currency:    req.Currency,
+		Departures:  days(first, last),
+	}
+	if maxTripLength > 0 {
+		c.Returns = days(first, last)
+	}
+
+	for i, t := range trips {
+		if errs[i] != nil || prices[i].IsZero() {
+			continue
+		}
+
+		cheaper(c.Departures, t.departure, prices[i], t.ret)
+		if c.Returns != nil {
+			cheaper(c.Returns, t.ret, prices[i], t.departure)
+		}
+	}
+
+	return c, nil
+}
+
+// priceDates prices every trip in one call when the provider can, keyed by
+// trip. Trips without a fare are left out, and nothing is priced when the call
+// fails so every trip falls back to explore and search.
+func (b *Builder) priceDates(
+	ctx context.Context,
+	req provider.Request,
+	trips []trip,
+	minTripLength, maxTripLength int,
+) map[string]money.Money {
+	dp, ok := b.p.(provider.DatePricer)
+	if !ok || len(trips) == 0 {
+		return nil
+	}
+
+	dates := provider.DatesRequest{
+		Origin:        req.Origin,
+		Destination:   req.Destination,
+		DepartureFrom: trips[0].departure,
+		DepartureTo:   trips[len(trips)-1].departure,
+		MinTripLength: minTripLength,
+		MaxTripLength: maxTripLength,
+		Party:         req.Party,
+		Currency:      req.Currency,
+		Class:         req.Class,
+	}
+	k := dates.Key()
+
+	b.mu.Lock()
+	dps, ok := b.dates[k]
+	b.mu.Unlock()
+	if !ok {
+		var err error
+		if dps, err = dp.Dates(ctx, dates); err != nil {
+			return nil
+		}
+
+		b.mu.Lock()
+		b.dates[k] = dps
+		b.mu.Unlock()
+	}
+
+	priced := make(map[string]money.Money, len(dps))
+	for _, dp := range dps {
+		k := trip{departure: dp.DepartureDate, ret: dp.ReturnDate}.key()
+		if price, ok := priced[k]; !ok || lower(dp.Price, price) {
+			priced[k] = dp.Price
+		}
+	}
+	return priced
+}
+
+// fare finds the cheapest fare for the request, trying the explore results
+// for the origin first as one explore covers every destination. Searches are
+// counted against MaxSearches, zero is returned once they run out.
+func (b *Builder) fare(ctx context.Context, req provider.Request, searches *atomic.Int32) (money.Money, error) {
+	eis, err := b.explore(ctx, req)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	price := money.Money{}
+	for _, ei := range eis {
+		if !ei.Reaches(req.Destination) {
+			continue
+		}
+		if price.IsZero() || lower(ei.Price, price) {
+			price = ei.Price
+		}
+	}
+	if !price.IsZero() {
+		return price, nil
+	}
+
+	return b.search(ctx, req, searches)
+}
+
+// explore is keyed without the destination, as it covers every destination.
+func (b *Builder) explore(ctx context.Context, req provider.Request) ([]itinery.ExploreItinery, error) {
+	explore := provider.ExploreRequest{
+		Origins:       []string{req.Origin},
+		DepartureFrom: req.DepartureDate,
+		Party:         req.Party,
+		Currency:      req.Currency,
+		Class:         req.Class,
+	}
+	if !req.ReturnDate.IsZero() {
+		explore.MinTripLength = req.TripLength()
+		explore.MaxTripLength = req.TripLength()
+	}
+	k := explore.Key()
+
+	b.mu.Lock()
+	eis, ok := b.explores[k]
+	b.mu.Unlock()
+	if ok {
+		return eis, nil
+	}
+
+	eis, err := b.p.Explore(ctx, explore)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	b.explores[k] = eis
+	b.mu.Unlock()
+
+	return eis, nil
+}
+
+func (b *Builder) search(ctx context.Context, req provider.Request, searches *atomic.Int32) (money.Money, error) {
+	k := req.Key()
+
+	b.mu.Lock()
+	price, ok := b.searches[k]
+	b.mu.Unlock()
+	if ok {
+		return price, nil
+	}
+	if searches.Add(1) > int32(b.MaxSearches) {
+		return money.Money{}, nil
+	}
+
+	itins, err := b.p.Search(ctx, req)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	if cheapest, ok := itinery.Cheapest(itins); ok {
+		price = cheapest.Price
+	}
+
+	b.mu.Lock()
+	b.searches[k] = price
+	b.mu.Unlock()
+
+	return price, nil
+}
+
+func days(first, last time.Time) []Day {
+	d := make([]Day, 0, last.Day())
+	for t := first; !t.After(last); t = t.AddDate(0, 0, 1) {
+		d = append(d, Day{Date: t})
+	}
+	return d
+}
+
+// cheaper records price on the day of date when it beats what's there. Dates
+// outside the month, such as returns spilling into the next, are ignored.
+func cheaper(d []Day, date time.Time, price money.Money, paired time.Time) {
+	i := date.Day() - 1
+	if i >= len(d) || !d[i].Date.Equal(date) {
+		return
+	}
+	if d[i].Price.IsZero() || lower(price, d[i].Price) {
+		d[i].Price = price
+		d[i].Paired = paired
+	}
+}
+
+// lower reports whether a is less than b, prices in another currency to the
+// calendar's are never lower.
+func lower(a, b money.Money) bool {
+	c, err := a.Cmp(b)
+	return err == nil && c < 0
+}
+
+func (c *Calendar) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		*Calendar
+		Currency string `json:"currency"`
+	}{c, c.Currency.String()})
+}
@@ -0,0 +1,380 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"github.com/tobyrushton/flyvia/packages/search/provider"
+	"github.com/tobyrushton/flyvia/packages/search/provider/providertest"
+	"golang.org/x/text/currency"
+)
+
+// fakeProvider explores and searches fakeFares, with explores on failDay
+// failing.
+func fakeProvider(failDay int) *providertest.Provider {
+	f := fakeFares{failDay: failDay}
+	return &providertest.Provider{ExploreFunc: f.explore, SearchFunc: f.search}
+}
+
+type fakeFares struct {
+	// day of the month whose explore fails.
+	failDay int
+}
+
+// explore prices JFK by departure day, the 10th being the cheapest. BOS is
+// never in explore results so has to be searched for.
+func (f fakeFares) explore(
+	_ context.Context,
+	req provider.ExploreRequest,
+) ([]itinery.ExploreItinery, error) {
+	if req.DepartureFrom.Day() == f.failDay {
+		return nil, errors.New("explore failed")
+	}
+
+	price := 300.0 + float64(req.DepartureFrom.Day())
+	if req.DepartureFrom.Day() == 10 {
+		price = 150
+	}
+	price += float64(req.MaxTripLength)
+
+	return []itinery.ExploreItinery{
+		{Destination: "JFK", City: "New York", Price: money.New(price, currency.GBP)},
+	}, nil
+}
+
+func (f fakeFares) search(
+	_ context.Context,
+	req provider.Request,
+) ([]itinery.Itinery, error) {
+	return []itinery.Itinery{
+		{Price: money.New(500, currency.GBP)},
+		{Price: money.New(450, currency.GBP)},
+	}, nil
+}
+
+// datesProvider prices every trip at 200 plus its length in one call, bar
+// departures on skipDay which are left to explore.
+type datesProvider struct {
+	*providertest.Provider
+	calls   int
+	skipDay int
+	// price every trip at this, for fares wider than the usual cells.
+	price float64
+}
+
+func (d *datesProvider) Dates(
+	_ context.Context,
+	req provider.DatesRequest,
+) ([]provider.DatePrice, error) {
+	d.calls++
+
+	prices := make([]provider.DatePrice, 0)
+	for dep := req.DepartureFrom; !dep.After(req.DepartureTo); dep = dep.AddDate(0, 0, 1) {
+		if dep.Day() == d.skipDay {
+			continue
+		}
+		for l := req.MinTripLength; l <= req.MaxTripLength; l++ {
+			price := d.price
+			if price == 0 {
+				price = 200 + float64(l)
+			}
+			p := provider.DatePrice{DepartureDate: dep, Price: money.New(price, currency.GBP)}
+			if !req.OneWay() {
+				p.ReturnDate = dep.AddDate(0, 0, l)
+			}
+			prices = append(prices, p)
+		}
+	}
+	return prices, nil
+}
+
+func TestBuild_PricesDatesInOneCall(t *testing.T) {
+	p := &datesProvider{Provider: fakeProvider(0), skipDay: 20}
+	b := NewBuilder(p)
+
+	req := provider.Request{Origin: "London", Destination: "JFK", Party: provider.Party{Adults: 1}, Currency: currency.GBP}
+	c, err := b.Build(context.Background(), req, 2030, time.February, 3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.calls != 1 {
+		t.Errorf("expected the month to be priced in one call, got %d", p.calls)
+	}
+	if c.Departures[0].Price != money.New(203, currency.GBP) {
+		t.Errorf("expected the 1st to cost 203, got %s", c.Departures[0].Price)
+	}
+	// the 20th has no date price so both lengths are explored.
+	if p.Explores() != 2 {
+		t.Errorf("expected only the 20th to be explored, got %d explores", p.Explores())
+	}
+	if c.Departures[19].Price != money.New(323, currency.GBP) {
+		t.Errorf("expected the 20th to be priced by explore at 323, got %s", c.Departures[19].Price)
+	}
+
+	if _, err := b.Build(context.Background(), req, 2030, time.February, 3, 4); err != nil {
+		t.Fatal(err)
+	}
+	if p.calls != 1 {
+		t.Errorf("expected a second calendar to come from the cache, got %d calls", p.calls)
+	}
+}
+
+func TestBuild_OneWay(t *testing.T) {
+	p := fakeProvider(0)
+	b := NewBuilder(p)
+
+	c, err := b.Build(
+		context.Background(),
+		provider.Request{Origin: "London", Destination: "JFK", Party: provider.Party{Adults: 1}, Currency: currency.GBP},
+		2030, time.February, 0, 0,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.Departures) != 28 {
+		t.Fatalf("expected 28 days in February 2030, got %d", len(c.Departures))
+	}
+	if c.Returns != nil {
+		t.Error("expected no return days for a one-way calendar")
+	}
+	if c.Departures[9].Price.Float64() != 150 {
+		t.Errorf("expected 10th to cost 150, got %s", c.Departures[9].Price)
+	}
+	if p.Searches() != 0 {
+		t.Errorf("expected explore to cover every day, got %d searches", p.Searches())
+	}
+
+	// a second calendar from the same origin should come from the cache.
+	if _, err := b.Build(
+		context.Background(),
+		provider.Request{Origin: "London", Destination: "New York", Party: provider.Party{Adults: 1}, Currency: currency.GBP},
+		2030, time.February, 0, 0,
+	); err != nil {
+		t.Fatal(err)
+	}
+	if p.Explores() != 28 {
+		t.Errorf("expected 28 explores after two calendars, got %d", p.Explores())
+	}
+}
+
+func TestBuild_CachesByParty(t *testing.T) {
+	p := fakeProvider(0)
+	b := NewBuilder(p)
+
+	req := provider.Request{Origin: "London", Destination: "JFK", Party: provider.Party{Adults: 1}}
+	if _, err := b.Build(context.Background(), req, 2030, time.February, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	req.InfantsOnLap = 1
+	if _, err := b.Build(context.Background(), req, 2030, time.February, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if p.Explores() != 56 {
+		t.Errorf("expected a lap infant to explore again, got %d explores", p.Explores())
+	}
+}
+
+func TestBuild_RoundTrip(t *testing.T) {
+	p := fakeProvider(0)
+
+	c, err := NewBuilder(p).Build(
+		context.Background(),
+		provider.Request{Origin: "London", Destination: "JFK", Party: provider.Party{Adults: 1}},
+		2030, time.February, 3, 4,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dep := c.Departures[9]
+	if dep.Price.Float64() != 153 {
+		t.Errorf("expected cheapest trip from the 10th to be 153, got %s", dep.Price)
+	}
+	if dep.Paired.Day() != 13 {
+		t.Errorf("expected cheapest trip from the 10th to return on the 13th, got %v", dep.Paired)
+	}
+
+	ret := c.Returns[12]
+	if ret.Price.Float64() != 153 || ret.Paired.Day() != 10 {
+		t.Errorf("expected cheapest return on the 13th to be 153 from the 10th, got %s from %v", ret.Price, ret.Paired)
+	}
+
+	// trips leaving in January fill the first returns of February.
+	first := c.Returns[0]
+	if first.Price.IsZero() || first.Paired.Month() != time.January {
+		t.Errorf("expected a return on the 1st from a January departure, got %s from %v", first.Price, first.Paired)
+	}
+	for _, d := range c.Departures {
+		if d.Date.Month() != time.February {
+			t.Errorf("expected only February departure days, got %v", d.Date)
+		}
+	}
+}
+
+func TestBuild_SkipsFailedDays(t *testing.T) {
+	p := fakeProvider(15)
+
+	c, err := NewBuilder(p).Build(
+		context.Background(),
+		provider.Request{Origin: "London", Destination: "JFK", Party: provider.Party{Adults: 1}},
+		2030, time.February, 0, 0,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !c.Departures[14].Price.IsZero() {
+		t.Errorf("expected the 15th to be empty, got %s", c.Departures[14].Price)
+	}
+	if c.Departures[15].Price.IsZero() {
+		t.Error("expected the days after a failed day to be filled")
+	}
+}
+
+func TestBuild_FallsBackToSearch(t *testing.T) {
+	p := fakeProvider(0)
+
+	c, err := NewBuilder(p).Build(
+		context.Background(),
+		provider.Request{Origin: "London", Destination: "BOS", Party: provider.Party{Adults: 1}},
+		2030, time.February, 0, 0,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Searches() != 28 {
+		t.Errorf("expected a search per day, got %d", p.Searches())
+	}
+	if c.Departures[0].Price.Float64() != 450 {
+		t.Errorf("expected cheapest searched fare of 450, got %s", c.Departures[0].Price)
+	}
+}
+
+func TestBuild_LimitsSearches(t *testing.T) {
+	p := fakeProvider(0)
+	b := NewBuilder(p)
+	b.MaxSearches = 5
+
+	c, err := b.Build(
+		context.Background(),
+		provider.Request{Origin: "London", Destination: "BOS", Party: provider.Party{Adults: 1}},
+		2030, time.February, 3, 4,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Searches() != 5 {
+		t.Errorf("expected searches to stop at 5, got %d", p.Searches())
+	}
+	filled := 0
+	for _, d := range c.Departures {
+		if !d.Price.IsZero() {
+			filled++
+		}
+	}
+	if filled > 5 {
+		t.Errorf("expected at most 5 days with a fare, got %d", filled)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	c, err := NewBuilder(fakeProvider(0)).Build(
+		context.Background(),
+		provider.Request{Origin: "London", Destination: "JFK", Party: provider.Party{Adults: 1}, Currency: currency.GBP},
+		2030, time.February, 0, 0,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.Buffer{}
+	if err := c.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var out struct {
+		Currency   string `json:"currency"`
+		Departures []Day  `json:"departures"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.Currency != "GBP" {
+		t.Errorf("expected GBP, got %s", out.Currency)
+	}
+	if len(out.Departures) != 28 || out.Departures[9].Price != money.New(150, currency.GBP) {
+		t.Error("expected departures to round trip through json")
+	}
+}
+
+func TestRender(t *testing.T) {
+	c, err := NewBuilder(fakeProvider(0)).Build(
+		context.Background(),
+		provider.Request{Origin: "London", Destination: "JFK", Party: provider.Party{Adults: 1}, Currency: currency.GBP},
+		2030, time.February, 0, 0,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.Buffer{}
+	if err := c.Render(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "February 2030") {
+		t.Error("expected the month in the title")
+	}
+	if !strings.Contains(out, "cheapest 150 on 2030-02-10") {
+		t.Errorf("expected legend to show the cheapest day, got:\n%s", out)
+	}
+}
+
+func TestRender_WideFares(t *testing.T) {
+	c, err := NewBuilder(&datesProvider{Provider: fakeProvider(0), price: 125000}).Build(
+		context.Background(),
+		provider.Request{Origin: "London", Destination: "JFK", Party: provider.Party{Adults: 1}, Currency: currency.GBP},
+		2030, time.February, 0, 0,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// leave a day empty to check it lines up too.
+	c.Departures[5].Price = money.Money{}
+
+	buf := bytes.Buffer{}
+	if err := c.Render(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	colour := regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	lines := strings.Split(colour.ReplaceAllString(buf.String(), ""), "\n")
+	// title, day names then four full weeks, february 2030 starts on a friday.
+	header, weeks := lines[1], lines[2:7]
+	if got := strings.Index(header, "Su"); got != 60 {
+		t.Errorf("expected 10 character columns, got sunday at %d in %q", got, header)
+	}
+	for _, w := range weeks[1:4] {
+		if len(w) != 70 {
+			t.Errorf("expected full weeks to be 70 characters, got %d in %q", len(w), w)
+		}
+	}
+	if !strings.HasPrefix(weeks[0], strings.Repeat(" ", 40)+" 1 125000") {
+		t.Errorf("expected the 1st under friday, got %q", weeks[0])
+	}
+}
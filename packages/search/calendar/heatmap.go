@@ -0,0 +1,139 @@
+package calendar
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// cheapest to dearest, 256 colour ansi backgrounds.
+var heat = []int{46, 118, 226, 208, 196}
+
+const reset = "\x1b[0m"
+
+// Render writes the calendar as a month-view heatmap for the terminal, each
+// day coloured by how its fare compares to the rest of the month.
+func (c *Calendar) Render(w io.Writer) error {
+	title := fmt.Sprintf("%s → %s  %s %d (%s)", c.Origin, c.Destination, c.Month, c.Year, c.Currency)
+
+	if err := renderMonth(w, "Departures  "+title, c.Departures); err != nil {
+		return err
+	}
+	if c.Returns == nil {
+		return nil
+	}
+
+	if _, err := fmt.Fprintln(w); err != nil {
+		return err
+	}
+	return renderMonth(w, "Returns  "+title, c.Returns)
+}
+
+func renderMonth(w io.Writer, title string, days []Day) error {
+	if len(days) == 0 {
+		return nil
+	}
+
+	thresholds := buckets(days)
+
+	// cells fit the widest fare, a day number either side of the price.
+	width := priceWidth(days)
+	cellWidth := width + 4
+
+	b := strings.Builder{}
+	b.WriteString(title + "\n")
+	for _, name := range []string{"Mo", "Tu", "We", "Th", "Fr", "Sa"} {
+		b.WriteString(name + strings.Repeat(" ", cellWidth-len(name)))
+	}
+	b.WriteString("Su\n")
+
+	// weeks start on monday, so sunday is the 7th column.
+	offset := (int(days[0].Date.Weekday()) + 6) % 7
+	b.WriteString(strings.Repeat(" ", offset*cellWidth))
+
+	for i, d := range days {
+		cell := fmt.Sprintf("%2d %*s ", d.Date.Day(), width, "-")
+		if !d.Price.IsZero() {
+			cell = fmt.Sprintf("\x1b[48;5;%dm\x1b[30m%2d %*.0f%s ", heat[bucket(thresholds, d.Price.Amount)], d.Date.Day(), width, d.Price.Float64(), reset)
+		}
+		b.WriteString(cell)
+
+		if (offset+i+1)%7 == 0 {
+			b.WriteString("\n")
+		}
+	}
+	if (offset+len(days))%7 != 0 {
+		b.WriteString("\n")
+	}
+
+	if len(thresholds) > 0 {
+		b.WriteString(legend(days))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// priceWidth is the width of the widest fare, at least four so months of
+// cheap fares keep their shape.
+func priceWidth(days []Day) int {
+	width := 4
+	for _, d := range days {
+		if !d.Price.IsZero() {
+			width = max(width, len(fmt.Sprintf("%.0f", d.Price.Float64())))
+		}
+	}
+	return width
+}
+
+// buckets splits the fares found into evenly sized groups by price.
+func buckets(days []Day) []int64 {
+	prices := make([]int64, 0, len(days))
+	for _, d := range days {
+		if !d.Price.IsZero() {
+			prices = append(prices, d.Price.Amount)
+		}
+	}
+	if len(prices) == 0 {
+		return nil
+	}
+	slices.Sort(prices)
+
+	thresholds := make([]int64, len(heat)-1)
+	for i := range thresholds {
+		thresholds[i] = prices[(i+1)*len(prices)/len(heat)]
+	}
+	return thresholds
+}
+
+func bucket(thresholds []int64, price int64) int {
+	for i, t := range thresholds {
+		if price < t {
+			return i
+		}
+	}
+	return len(thresholds)
+}
+
+func legend(days []Day) string {
+	var cheapest, dearest Day
+	for _, d := range days {
+		if d.Price.IsZero() {
+			continue
+		}
+		if cheapest.Price.IsZero() || lower(d.Price, cheapest.Price) {
+			cheapest = d
+		}
+		if dearest.Price.IsZero() || lower(dearest.Price, d.Price) {
+			dearest = d
+		}
+	}
+
+	return fmt.Sprintf(
+		"cheapest %.0f on %s, dearest %.0f on %s\n",
+		cheapest.Price.Float64(), cheapest.Date.Format(time.DateOnly),
+		dearest.Price.Float64(), dearest.Date.Format(time.DateOnly),
+	)
+}
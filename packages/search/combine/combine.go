@@ -1,16 +1,22 @@
 package combine
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/tobyrushton/flyvia/packages/search"
 	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/money"
 )
 
+// OneStop pairs every first ticket with the second tickets leaving from where
+// it lands within the layover. Pairs priced in different currencies can't be
+// totalled, they are left out and counted in the returned error, which wraps
+// money.ErrCurrencyMismatch, alongside the pairs that could be combined.
 func OneStop(
 	firstItineries, secondItineries []itinery.Itinery,
 	minLayover, maxLayover time.Duration,
-) []search.Result {
+) ([]search.Result, error) {
 	// this current setup will only connect airports, however a lot of major cities will have multiple airports
 	// likely is the case that the flights will fly from different airports as regional and major long haul flights
 	// often fly from different airports. This will need to be taken into account later.
@@ -20,16 +26,26 @@ func OneStop(
 	}
 
 	results := make([]search.Result, 0)
+	mismatched := 0
 
 	for _, first := range firstItineries {
 		candidates := index[first.Outbound.ArrivalAirport]
 
 		for _, second := range candidates {
-			if validLayover(first, second, minLayover, maxLayover) {
-				results = append(results, search.NewResult(first, second))
+			if !validLayover(first, second, minLayover, maxLayover) {
+				continue
 			}
+			r, err := search.NewResult(first, second)
+			if err != nil {
+				mismatched++
+				continue
+			}
+			results = append(results, r)
 		}
 	}
 
-	return results
+	if mismatched > 0 {
+		return results, fmt.Errorf("%w: %d connections left out", money.ErrCurrencyMismatch, mismatched)
+	}
+	return results, nil
 }
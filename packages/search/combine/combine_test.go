@@ -1,11 +1,15 @@
 package combine
 
 import (
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/tobyrushton/flyvia/packages/search/itinery"
 	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"github.com/tobyrushton/flyvia/packages/search/provider"
+	"golang.org/x/text/currency"
 )
 
 // Helper function to create a basic leg
@@ -35,7 +39,7 @@ func createItinerary(outboundLeg, inboundLeg leg.Leg, price float64) itinery.Iti
 	return itinery.Itinery{
 		Outbound:   outboundLeg,
 		Inbound:    inboundLeg,
-		Price:      price,
+		Price:      money.New(price, currency.GBP),
 		BookingURL: "https://example.com/book",
 	}
 }
@@ -51,12 +55,15 @@ func TestOneStop_EmptyFirstItineraries(t *testing.T) {
 		),
 	}
 
-	results := OneStop(
+	results, err := OneStop(
 		[]itinery.Itinery{},
 		secondItineraries,
 		1*time.Hour,
 		6*time.Hour,
 	)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if len(results) != 0 {
 		t.Errorf("Expected 0 results with empty first itineraries, got %d", len(results))
@@ -74,12 +81,15 @@ func TestOneStop_EmptySecondItineraries(t *testing.T) {
 		),
 	}
 
-	results := OneStop(
+	results, err := OneStop(
 		firstItineraries,
 		[]itinery.Itinery{},
 		1*time.Hour,
 		6*time.Hour,
 	)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if len(results) != 0 {
 		t.Errorf("Expected 0 results with empty second itineraries, got %d", len(results))
@@ -87,12 +97,15 @@ func TestOneStop_EmptySecondItineraries(t *testing.T) {
 }
 
 func TestOneStop_BothEmpty(t *testing.T) {
-	results := OneStop(
+	results, err := OneStop(
 		[]itinery.Itinery{},
 		[]itinery.Itinery{},
 		1*time.Hour,
 		6*time.Hour,
 	)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if len(results) != 0 {
 		t.Errorf("Expected 0 results with both empty, got %d", len(results))
@@ -118,12 +131,15 @@ func TestOneStop_NoMatchingAirports(t *testing.T) {
 		),
 	}
 
-	results := OneStop(
+	results, err := OneStop(
 		firstItineraries,
 		secondItineraries,
 		1*time.Hour,
 		6*time.Hour,
 	)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if len(results) != 0 {
 		t.Errorf("Expected 0 results with no matching airports, got %d", len(results))
@@ -151,12 +167,15 @@ func TestOneStop_ValidConnection(t *testing.T) {
 		),
 	}
 
-	results := OneStop(
+	results, err := OneStop(
 		firstItineraries,
 		secondItineraries,
 		1*time.Hour,
 		6*time.Hour,
 	)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if len(results) != 1 {
 		t.Errorf("Expected 1 result with valid connection, got %d", len(results))
@@ -184,12 +203,15 @@ func TestOneStop_LayoverTooShort(t *testing.T) {
 		),
 	}
 
-	results := OneStop(
+	results, err := OneStop(
 		firstItineraries,
 		secondItineraries,
 		1*time.Hour,
 		6*time.Hour,
 	)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if len(results) != 0 {
 		t.Errorf("Expected 0 results with layover too short, got %d", len(results))
@@ -217,12 +239,15 @@ func TestOneStop_LayoverTooLong(t *testing.T) {
 		),
 	}
 
-	results := OneStop(
+	results, err := OneStop(
 		firstItineraries,
 		secondItineraries,
 		1*time.Hour,
 		6*time.Hour,
 	)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if len(results) != 0 {
 		t.Errorf("Expected 0 results with layover too long, got %d", len(results))
@@ -260,12 +285,15 @@ func TestOneStop_MultipleValidConnections(t *testing.T) {
 		),
 	}
 
-	results := OneStop(
+	results, err := OneStop(
 		firstItineraries,
 		secondItineraries,
 		1*time.Hour,
 		6*time.Hour,
 	)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if len(results) != 3 {
 		t.Errorf("Expected 3 results with multiple valid connections, got %d", len(results))
@@ -297,12 +325,15 @@ func TestOneStop_MultipleFirstItineraries(t *testing.T) {
 		),
 	}
 
-	results := OneStop(
+	results, err := OneStop(
 		firstItineraries,
 		secondItineraries,
 		1*time.Hour,
 		6*time.Hour,
 	)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if len(results) != 2 {
 		t.Errorf("Expected 2 results (one for each first itinerary), got %d", len(results))
@@ -340,12 +371,15 @@ func TestOneStop_SameAirportMultipleTimes(t *testing.T) {
 		),
 	}
 
-	results := OneStop(
+	results, err := OneStop(
 		firstItineraries,
 		secondItineraries,
 		1*time.Hour,
 		6*time.Hour,
 	)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// Should get 4 results: each first itinerary can connect to each second itinerary
 	if len(results) != 4 {
@@ -374,12 +408,15 @@ func TestOneStop_ZeroLayoverDuration(t *testing.T) {
 		),
 	}
 
-	results := OneStop(
+	results, err := OneStop(
 		firstItineraries,
 		secondItineraries,
 		0*time.Hour,
 		6*time.Hour,
 	)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// This tests behavior with zero-duration layover - might be valid depending on validLayover implementation
 	// Adjust expected result based on actual validLayover behavior
@@ -409,12 +446,15 @@ func TestOneStop_NegativeSecondFlight(t *testing.T) {
 		),
 	}
 
-	results := OneStop(
+	results, err := OneStop(
 		firstItineraries,
 		secondItineraries,
 		1*time.Hour,
 		6*time.Hour,
 	)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if len(results) != 0 {
 		t.Errorf("Expected 0 results when second flight departs before first arrives, got %d", len(results))
@@ -442,12 +482,15 @@ func TestOneStop_BoundaryLayoverMinimum(t *testing.T) {
 		),
 	}
 
-	results := OneStop(
+	results, err := OneStop(
 		firstItineraries,
 		secondItineraries,
 		1*time.Hour,
 		6*time.Hour,
 	)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// Behavior depends on whether validLayover uses >= or > for minimum
 	t.Logf("With exactly minimum layover (1 hour), got %d results", len(results))
@@ -474,12 +517,15 @@ func TestOneStop_BoundaryLayoverMaximum(t *testing.T) {
 		),
 	}
 
-	results := OneStop(
+	results, err := OneStop(
 		firstItineraries,
 		secondItineraries,
 		1*time.Hour,
 		6*time.Hour,
 	)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// Behavior depends on whether validLayover uses <= or < for maximum
 	t.Logf("With exactly maximum layover (6 hours), got %d results", len(results))
@@ -525,12 +571,15 @@ func TestOneStop_MixedValidAndInvalid(t *testing.T) {
 		),
 	}
 
-	results := OneStop(
+	results, err := OneStop(
 		firstItineraries,
 		secondItineraries,
 		1*time.Hour,
 		6*time.Hour,
 	)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if len(results) != 2 {
 		t.Errorf("Expected 2 valid results from mixed connections, got %d", len(results))
@@ -557,12 +606,15 @@ func TestOneStop_DifferentAirportCodes(t *testing.T) {
 		),
 	}
 
-	results := OneStop(
+	results, err := OneStop(
 		firstItineraries,
 		secondItineraries,
 		1*time.Hour,
 		6*time.Hour,
 	)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if len(results) != 1 {
 		t.Errorf("Expected 1 result with different airport codes, got %d", len(results))
@@ -590,12 +642,15 @@ func TestOneStop_CaseSensitivity(t *testing.T) {
 		),
 	}
 
-	results := OneStop(
+	results, err := OneStop(
 		firstItineraries,
 		secondItineraries,
 		1*time.Hour,
 		6*time.Hour,
 	)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// This will reveal if the function is case-sensitive (expected: 0 if case-sensitive)
 	t.Logf("With case mismatch (JFK vs jfk), got %d results", len(results))
@@ -624,12 +679,15 @@ func TestOneStop_LargeDataset(t *testing.T) {
 		)
 	}
 
-	results := OneStop(
+	results, err := OneStop(
 		firstItineraries,
 		secondItineraries,
 		1*time.Hour,
 		6*time.Hour,
 	)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// Should have many valid connections
 	if len(results) == 0 {
@@ -637,3 +695,56 @@ func TestOneStop_LargeDataset(t *testing.T) {
 	}
 	t.Logf("Large dataset test produced %d results", len(results))
 }
+
+func TestMinLayover(t *testing.T) {
+	tests := []struct {
+		name string
+		req  provider.Request
+		want time.Duration
+	}{
+		{"adults", provider.Request{Party: provider.Party{Adults: 2}}, time.Hour},
+		{"infant", provider.Request{Party: provider.Party{Adults: 2, InfantsOnLap: 1}}, time.Hour + infantLayover},
+		{"no ages", provider.Request{Party: provider.Party{Adults: 2, Children: 1}}, time.Hour + youngChildLayover},
+		{"young child", provider.Request{Party: provider.Party{Adults: 2, Children: 2, ChildAges: []int{10, 3}}}, time.Hour + youngChildLayover},
+		{"older children", provider.Request{Party: provider.Party{Adults: 2, Children: 2, ChildAges: []int{10, 7}}}, time.Hour + childLayover},
+	}
+
+	for _, tt := range tests {
+		if got := MinLayover(time.Hour, tt.req); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestOneStop_MixedCurrencies(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	firstItineraries := []itinery.Itinery{
+		createItinerary(
+			createLeg("LHR", "JFK", baseTime, baseTime.Add(8*time.Hour)),
+			createLeg("JFK", "LHR", baseTime.Add(72*time.Hour), baseTime.Add(80*time.Hour)),
+			800.0,
+		),
+	}
+
+	inGBP := createItinerary(
+		createLeg("JFK", "LAX", baseTime.Add(11*time.Hour), baseTime.Add(16*time.Hour)),
+		createLeg("LAX", "JFK", baseTime.Add(48*time.Hour), baseTime.Add(53*time.Hour)),
+		500.0,
+	)
+	inUSD := inGBP
+	inUSD.Price = money.New(600, currency.USD)
+
+	results, err := OneStop(
+		firstItineraries,
+		[]itinery.Itinery{inGBP, inUSD},
+		1*time.Hour,
+		6*time.Hour,
+	)
+	if !errors.Is(err, money.ErrCurrencyMismatch) {
+		t.Errorf("Expected a currency mismatch for the USD ticket, got %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("Expected the GBP pair to still be combined, got %d results", len(results))
+	}
+}
@@ -4,8 +4,21 @@ import (
 	"time"
 
 	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/provider"
 )
 
+// extra time families need at a self-transfer to collect bags, get buggies
+// and car seats back and go through security again.
+const (
+	infantLayover     = 45 * time.Minute
+	youngChildLayover = 30 * time.Minute
+	childLayover      = 15 * time.Minute
+)
+
+// children under this age count as young, when ages aren't given every child
+// is assumed to be.
+const youngChildAge = 6
+
 func validLayover(
 	first, second itinery.Itinery,
 	minLayover, maxLayover time.Duration,
@@ -13,3 +26,17 @@ func validLayover(
 	layover := second.Outbound.DepartureTime.Sub(first.Outbound.ArrivalTime)
 	return layover >= minLayover && layover <= maxLayover
 }
+
+// MinLayover extends minLayover for the slowest of req's passengers.
+func MinLayover(minLayover time.Duration, req provider.Request) time.Duration {
+	if req.Infants() > 0 {
+		return minLayover + infantLayover
+	}
+	if req.Children == 0 {
+		return minLayover
+	}
+	if youngest, ok := req.YoungestChild(); ok && youngest >= youngChildAge {
+		return minLayover + childLayover
+	}
+	return minLayover + youngChildLayover
+}
@@ -0,0 +1,150 @@
+package search
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/airport"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+)
+
+type ConnectionKind string
+
+const (
+	// only time to get to the next flight.
+	Tight ConnectionKind = "tight"
+	// time to get into the city and back.
+	LeaveAirport ConnectionKind = "leave-airport"
+	// through the night, needing a hotel.
+	Overnight ConnectionKind = "overnight"
+	// a day or more, a stay rather than a layover.
+	MultiDay ConnectionKind = "multi-day"
+)
+
+// time spent in the airport at a self-transfer, getting out of arrivals and
+// back through check-in and security, longer when bags are checked in again.
+const (
+	airportTime         = 2 * time.Hour
+	airportTimeWithBags = 150 * time.Minute
+	// any less in the city isn't worth the trip in.
+	minCityTime = 2 * time.Hour
+	// a layover over the whole of these hours, local time, needs a bed.
+	nightStart = 1
+	nightEnd   = 5
+)
+
+// transit time to the city for airports missing from the dataset.
+const defaultCityTransit = 45 * time.Minute
+
+type Connection struct {
+	Airport   string
+	Arrival   time.Time
+	Departure time.Time
+	Layover   time.Duration
+	Kind      ConnectionKind
+	// time free in the city once there and back and through the airport
+	// are taken off, zero when there's none.
+	CityTime time.Duration
+	// nights a hotel is needed for.
+	Nights int
+}
+
+// Classify returns r with each connection between tickets classified, and
+// the hotel nights they need costed at hotelPerNight for the party. A zero
+// hotelPerNight leaves hotels out of the total, as does one in another
+// currency to r, with a warning.
+func Classify(r Result, hotelPerNight money.Money) Result {
+	pairs := connectingLegs(r.Itineries)
+	r.Connections = make([]Connection, 0, len(pairs))
+	nights := 0
+
+	for _, pair := range pairs {
+		c := classify(pair[0], pair[1], r.RecollectBags)
+		nights += c.Nights
+		r.Connections = append(r.Connections, c)
+	}
+
+	r.HotelCost = money.Money{}
+	if hotelPerNight.IsZero() || nights == 0 {
+		return r
+	}
+	if hotelPerNight.Currency != r.Price.Currency {
+		r.Warnings = append(slices.Clone(r.Warnings), fmt.Sprintf(
+			"hotel cost in %s not converted, left out of the total", hotelPerNight.Currency,
+		))
+		return r
+	}
+	r.HotelCost = hotelPerNight.Mul(float64(nights))
+
+	return r
+}
+
+func classify(arriving, departing leg.Leg, recollectBags bool) Connection {
+	c := Connection{
+		Airport:   arriving.ArrivalAirport,
+		Arrival:   arriving.ArrivalTime,
+		Departure: departing.DepartureTime,
+		Layover:   departing.DepartureTime.Sub(arriving.ArrivalTime),
+	}
+
+	transit := defaultCityTransit
+	if a, ok := airport.Lookup(c.Airport); ok && a.CityTransit > 0 {
+		transit = a.CityTransit
+	}
+	inAirport := airportTime
+	if recollectBags {
+		inAirport = airportTimeWithBags
+	}
+	c.CityTime = max(0, c.Layover-inAirport-2*transit)
+	c.Nights = nights(c.Arrival, c.Departure)
+
+	switch {
+	case c.Layover >= 24*time.Hour:
+		c.Kind = MultiDay
+	case c.Nights > 0:
+		c.Kind = Overnight
+	case c.CityTime >= minCityTime:
+		c.Kind = LeaveAirport
+	default:
+		c.Kind = Tight
+	}
+
+	return c
+}
+
+// nights counts the nights between arrival and departure that take in all of
+// the small hours, both being local time at the airport.
+func nights(arrival, departure time.Time) int {
+	n := 0
+	day := time.Date(arrival.Year(), arrival.Month(), arrival.Day(), 0, 0, 0, 0, arrival.Location())
+	for ; day.Before(departure); day = day.AddDate(0, 0, 1) {
+		start := day.Add(nightStart * time.Hour)
+		end := day.Add(nightEnd * time.Hour)
+		if !arrival.After(start) && !departure.Before(end) {
+			n++
+		}
+	}
+	return n
+}
+
+// connectingLegs pairs the leg arriving at each change of ticket with the
+// one leaving it. A pair of return tickets changes at the hub both ways,
+// one-way tickets change one after another.
+func connectingLegs(itins []itinery.Itinery) [][2]leg.Leg {
+	pairs := make([][2]leg.Leg, 0, len(itins))
+
+	if len(itins) == 2 && len(itins[0].Inbound.Flights) > 0 && len(itins[1].Inbound.Flights) > 0 {
+		return append(pairs,
+			[2]leg.Leg{itins[0].Outbound, itins[1].Outbound},
+			[2]leg.Leg{itins[1].Inbound, itins[0].Inbound},
+		)
+	}
+
+	for i := 1; i < len(itins); i++ {
+		pairs = append(pairs, [2]leg.Leg{itins[i-1].Outbound, itins[i].Outbound})
+	}
+	return pairs
+}
@@ -0,0 +1,105 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"golang.org/x/text/currency"
+)
+
+var day = time.Date(2030, 4, 10, 0, 0, 0, 0, time.UTC)
+
+func oneWay(from, to string, dep time.Time) itinery.Itinery {
+	return itinery.Itinery{
+		Outbound: leg.Leg{
+			Flights:          []leg.Flight{{DepartureAirport: from, ArrivalAirport: to}},
+			DepartureAirport: from,
+			ArrivalAirport:   to,
+			DepartureTime:    dep,
+			ArrivalTime:      dep.Add(2 * time.Hour),
+		},
+		Price: money.New(100, currency.GBP),
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name    string
+		arrival time.Duration // after midnight, the onward flight leaves at departure
+		depart  time.Duration
+		want    ConnectionKind
+	}{
+		{"tight", 10 * time.Hour, 13 * time.Hour, Tight},
+		// 9 hours at AMS, 20 minutes each way to the city.
+		{"long enough to leave", 8 * time.Hour, 17 * time.Hour, LeaveAirport},
+		{"overnight", 22 * time.Hour, 31 * time.Hour, Overnight},
+		{"late evening", 18 * time.Hour, 24 * time.Hour, LeaveAirport},
+		{"multi-day", 10 * time.Hour, 58 * time.Hour, MultiDay},
+	}
+
+	for _, tt := range tests {
+		r, err := NewChain(
+			oneWay("LHR", "AMS", day.Add(tt.arrival-2*time.Hour)),
+			oneWay("AMS", "JFK", day.Add(tt.depart)),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r = Classify(r, money.Money{})
+		if len(r.Connections) != 1 {
+			t.Fatalf("%s: expected 1 connection, got %d", tt.name, len(r.Connections))
+		}
+		if got := r.Connections[0].Kind; got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestClassify_HotelCost(t *testing.T) {
+	r, err := NewChain(
+		oneWay("LHR", "AMS", day.Add(20*time.Hour)),
+		oneWay("AMS", "JFK", day.Add(56*time.Hour)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r = Classify(r, money.New(90, currency.GBP))
+
+	if r.Connections[0].Nights != 2 {
+		t.Errorf("expected 2 nights in Amsterdam, got %d", r.Connections[0].Nights)
+	}
+	total, err := r.Total()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != money.New(380, currency.GBP) {
+		t.Errorf("expected 200 of flights and 180 of hotels, got %s", total)
+	}
+}
+
+func TestClassify_HotelInAnotherCurrency(t *testing.T) {
+	r, err := NewChain(
+		oneWay("LHR", "AMS", day.Add(20*time.Hour)),
+		oneWay("AMS", "JFK", day.Add(56*time.Hour)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r = Classify(r, money.New(90, currency.EUR))
+
+	if !r.HotelCost.IsZero() {
+		t.Errorf("expected a EUR hotel to be left out of a GBP result, got %s", r.HotelCost)
+	}
+	if len(r.Warnings) != 1 {
+		t.Errorf("expected a warning about the hotel currency, got %v", r.Warnings)
+	}
+	if total, err := r.Total(); err != nil || total != money.New(200, currency.GBP) {
+		t.Errorf("expected the total to still be the flights' 200, got %s %v", total, err)
+	}
+}
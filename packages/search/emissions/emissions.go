@@ -0,0 +1,143 @@
+package emissions
+
+import (
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/aircraft"
+	"github.com/tobyrushton/flyvia/packages/search/airport"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+)
+
+// Estimates follow the UK government (DEFRA) greenhouse gas conversion
+// factors for business travel: great-circle distance plus an uplift for
+// routing and holding, times a factor per passenger-km by length of flight and
+// cabin. Factors are kg CO2e without radiative forcing.
+const (
+	distanceUplift = 1.08
+	domesticKm     = 500
+	shortHaulKm    = 3700
+)
+
+type haul int
+
+const (
+	domestic haul = iota
+	shortHaul
+	longHaul
+)
+
+// kg CO2e per passenger-km.
+var factors = map[haul]map[leg.Cabin]float64{
+	domestic: {
+		leg.Economy:        0.246,
+		leg.PremiumEconomy: 0.246,
+		leg.Business:       0.246,
+		leg.First:          0.246,
+	},
+	shortHaul: {
+		leg.Economy:        0.151,
+		leg.PremiumEconomy: 0.227,
+		leg.Business:       0.227,
+		leg.First:          0.227,
+	},
+	longHaul: {
+		leg.Economy:        0.148,
+		leg.PremiumEconomy: 0.237,
+		leg.Business:       0.429,
+		leg.First:          0.592,
+	},
+}
+
+func haulOf(km float64) haul {
+	switch {
+	case km < domesticKm:
+		return domestic
+	case km < shortHaulKm:
+		return shortHaul
+	}
+	return longHaul
+}
+
+// aircraft burn more or less than the average the factors are based on.
+func aircraftFactor(f leg.Flight) float64 {
+	if a, ok := aircraft.Of(f); ok {
+		return a.Efficiency
+	}
+	return 1
+}
+
+func cabinFactor(h haul, cabin leg.Cabin) float64 {
+	if f, ok := factors[h][cabin]; ok {
+		return f
+	}
+	return factors[h][leg.Economy]
+}
+
+// Flight is the kg of CO2e one passenger is responsible for on f, false when
+// either airport isn't known.
+func Flight(f leg.Flight) (float64, bool) {
+	km, ok := airport.Distance(f.DepartureAirport, f.ArrivalAirport)
+	if !ok {
+		return 0, false
+	}
+	km *= distanceUplift
+	return km * cabinFactor(haulOf(km), f.Cabin) * aircraftFactor(f), true
+}
+
+// Direct is the kg of CO2e per passenger of flying nonstop between two
+// airports on an average aircraft.
+func Direct(from, to string, cabin leg.Cabin) (float64, bool) {
+	return Flight(leg.Flight{DepartureAirport: from, ArrivalAirport: to, Cabin: cabin})
+}
+
+// Itinery sums every flight of itin, false when any couldn't be estimated.
+func Itinery(itin itinery.Itinery) (float64, bool) {
+	total := 0.0
+	for _, l := range []leg.Leg{itin.Outbound, itin.Inbound} {
+		for _, f := range l.Flights {
+			kg, ok := Flight(f)
+			if !ok {
+				return 0, false
+			}
+			total += kg
+		}
+	}
+	return total, true
+}
+
+// Apply returns r with its emissions per passenger and those of flying
+// direct from its origin to its destination in the same cabin. Results that
+// couldn't be estimated are left as they were.
+func Apply(r search.Result) search.Result {
+	if len(r.Itineries) == 0 {
+		return r
+	}
+
+	total := 0.0
+	for _, itin := range r.Itineries {
+		kg, ok := Itinery(itin)
+		if !ok {
+			return r
+		}
+		total += kg
+	}
+	r.CO2 = total
+
+	first, last := r.Itineries[0], r.Itineries[len(r.Itineries)-1]
+	origin, dest := first.Outbound.DepartureAirport, last.Outbound.ArrivalAirport
+	// a journey ending where it started has no direct flight to compare to.
+	if origin == dest || len(first.Outbound.Flights) == 0 {
+		return r
+	}
+
+	direct, ok := Direct(origin, dest, first.Outbound.Flights[0].Cabin)
+	if !ok {
+		return r
+	}
+	if len(first.Inbound.Flights) > 0 {
+		direct *= 2
+	}
+	r.DirectCO2 = direct
+
+	return r
+}
@@ -0,0 +1,95 @@
+package emissions
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/airport"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+)
+
+func flight(from, to, plane string, cabin leg.Cabin) leg.Flight {
+	return leg.Flight{DepartureAirport: from, ArrivalAirport: to, Plane: plane, Cabin: cabin}
+}
+
+func oneWay(flights ...leg.Flight) itinery.Itinery {
+	return itinery.Itinery{Outbound: leg.Leg{
+		Flights:          flights,
+		DepartureAirport: flights[0].DepartureAirport,
+		ArrivalAirport:   flights[len(flights)-1].ArrivalAirport,
+	}}
+}
+
+func TestFlight(t *testing.T) {
+	km, _ := airport.Distance("LHR", "JFK")
+	want := km * distanceUplift * 0.148
+
+	got, ok := Flight(flight("LHR", "JFK", "Boeing 777", leg.Economy))
+	if !ok || math.Abs(got-want) > 0.01 {
+		t.Errorf("expected %.2f kg, got %.2f", want, got)
+	}
+
+	business, _ := Flight(flight("LHR", "JFK", "Boeing 777", leg.Business))
+	if business <= got {
+		t.Errorf("expected business to emit more than economy, got %.2f and %.2f", business, got)
+	}
+
+	newer, _ := Flight(flight("LHR", "JFK", "Boeing 787-9", leg.Economy))
+	if math.Abs(newer-want*0.85) > 0.01 {
+		t.Errorf("expected a 787 to emit less, got %.2f", newer)
+	}
+
+	if _, ok := Flight(flight("LHR", "XXX", "", leg.Economy)); ok {
+		t.Error("expected an unknown airport not to be estimated")
+	}
+}
+
+func TestHaul(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     haul
+	}{
+		{"LHR", "MAN", domestic},
+		{"LHR", "DOH", longHaul},
+		{"LHR", "CDG", domestic},
+		{"MAN", "DOH", longHaul},
+		{"EDI", "CDG", shortHaul},
+	}
+
+	for _, tt := range tests {
+		km, _ := airport.Distance(tt.from, tt.to)
+		if got := haulOf(km * distanceUplift); got != tt.want {
+			t.Errorf("%s-%s: expected haul %d, got %d", tt.from, tt.to, tt.want, got)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	r := search.Result{Itineries: []itinery.Itinery{
+		oneWay(flight("LHR", "DOH", "", leg.Economy)),
+		oneWay(flight("DOH", "BKK", "", leg.Economy)),
+	}}
+
+	r = Apply(r)
+
+	a, _ := Flight(flight("LHR", "DOH", "", leg.Economy))
+	b, _ := Flight(flight("DOH", "BKK", "", leg.Economy))
+	if math.Abs(r.CO2-(a+b)) > 0.01 {
+		t.Errorf("expected %.2f kg, got %.2f", a+b, r.CO2)
+	}
+
+	direct, _ := Direct("LHR", "BKK", leg.Economy)
+	if math.Abs(r.DirectCO2-direct) > 0.01 {
+		t.Errorf("expected the direct flight to emit %.2f kg, got %.2f", direct, r.DirectCO2)
+	}
+	if r.DirectCO2 >= r.CO2 {
+		t.Errorf("expected flying via DOH to emit more than direct, got %.2f and %.2f", r.CO2, r.DirectCO2)
+	}
+
+	unknown := Apply(search.Result{Itineries: []itinery.Itinery{oneWay(flight("LHR", "XXX", "", leg.Economy))}})
+	if unknown.CO2 != 0 || unknown.DirectCO2 != 0 {
+		t.Errorf("expected no estimate for an unknown airport, got %+v", unknown)
+	}
+}
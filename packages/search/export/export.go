@@ -0,0 +1,296 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+)
+
+type jsonFlight struct {
+	FlightCode  string    `json:"flight_code"`
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	Departure   time.Time `json:"departure"`
+	Arrival     time.Time `json:"arrival"`
+	Airline     string    `json:"airline"`
+	AirlineCode string    `json:"airline_code"`
+	// only set for codeshares.
+	OperatedBy     string `json:"operated_by,omitempty"`
+	OperatedByCode string `json:"operated_by_code,omitempty"`
+	Plane          string `json:"plane,omitempty"`
+	Aircraft       string `json:"aircraft,omitempty"`
+	Cabin          string `json:"cabin"`
+}
+
+type jsonConditions struct {
+	Brand         string      `json:"brand,omitempty"`
+	Refundable    bool        `json:"refundable"`
+	Changeable    bool        `json:"changeable"`
+	ChangeFee     money.Money `json:"change_fee,omitzero"`
+	SeatSelection bool        `json:"seat_selection"`
+	CarryOn       bool        `json:"carry_on"`
+	CheckedBags   int         `json:"checked_bags"`
+	Estimated     bool        `json:"estimated,omitempty"`
+}
+
+type jsonTicket struct {
+	Price      money.Money    `json:"price"`
+	BookingURL string         `json:"booking_url,omitempty"`
+	Conditions jsonConditions `json:"conditions"`
+	Outbound   []jsonFlight   `json:"outbound"`
+	Inbound    []jsonFlight   `json:"inbound,omitempty"`
+}
+
+type jsonPassengers struct {
+	Type  itinery.PassengerType `json:"type"`
+	Count int                   `json:"count"`
+	Total money.Money           `json:"total"`
+}
+
+type jsonBooking struct {
+	// 1-based, matching the text output.
+	Step       int              `json:"step"`
+	Ticket     int              `json:"ticket"`
+	From       string           `json:"from"`
+	To         string           `json:"to"`
+	Departure  time.Time        `json:"departure"`
+	Price      money.Money      `json:"price"`
+	BookingURL string           `json:"booking_url,omitempty"`
+	Passengers []jsonPassengers `json:"passengers,omitempty"`
+}
+
+type jsonPlan struct {
+	Tickets  money.Money   `json:"tickets_total"`
+	Bookings []jsonBooking `json:"bookings"`
+}
+
+type jsonResult struct {
+	StopCity        string         `json:"stop_city"`
+	Price           money.Money    `json:"price"`
+	Total           money.Money    `json:"total,omitzero"`
+	BagFees         money.Money    `json:"bag_fees,omitzero"`
+	HotelCost       money.Money    `json:"hotel_cost,omitzero"`
+	MissProbability float64        `json:"miss_probability,omitempty"`
+	CO2             float64        `json:"co2_kg,omitempty"`
+	DirectCO2       float64        `json:"direct_co2_kg,omitempty"`
+	Points          map[string]int `json:"points,omitempty"`
+	StatusCredits   map[string]int `json:"status_credits,omitempty"`
+	ObservedAt      time.Time      `json:"observed_at,omitzero"`
+	Verified        bool           `json:"verified"`
+	VerifiedAt      time.Time      `json:"verified_at,omitzero"`
+	PriceChange     money.Money    `json:"price_change,omitzero"`
+	SoldOut         bool           `json:"sold_out,omitempty"`
+	Tickets         []jsonTicket   `json:"tickets"`
+	BookingPlan     jsonPlan       `json:"booking_plan"`
+	Warnings        []string       `json:"warnings,omitempty"`
+}
+
+// JSON writes results as an indented json array.
+func JSON(w io.Writer, results []search.Result) error {
+	out := make([]jsonResult, len(results))
+	for i, r := range results {
+		out[i] = toJSON(r)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func toJSON(r search.Result) jsonResult {
+	jr := jsonResult{
+		StopCity:        r.StopCity,
+		Price:           r.Price,
+		BagFees:         r.BagFees,
+		HotelCost:       r.HotelCost,
+		MissProbability: r.MissProbability,
+		CO2:             r.CO2,
+		DirectCO2:       r.DirectCO2,
+		Points:          r.Points,
+		StatusCredits:   r.StatusCredits,
+		ObservedAt:      r.ObservedAt,
+		Verified:        r.Verified,
+		VerifiedAt:      r.VerifiedAt,
+		PriceChange:     r.PriceChange,
+		SoldOut:         r.SoldOut,
+		Tickets:         make([]jsonTicket, len(r.Itineries)),
+		BookingPlan:     toJSONPlan(r.BookingPlan()),
+		Warnings:        r.Warnings,
+	}
+	// a total in mixed currencies can't be given.
+	if total, err := r.Total(); err == nil {
+		jr.Total = total
+	}
+
+	for i, itin := range r.Itineries {
+		jr.Tickets[i] = jsonTicket{
+			Price:      itin.Price,
+			BookingURL: itin.BookingURL,
+			Conditions: jsonConditions(itin.Conditions),
+			Outbound:   jsonFlights(itin.Outbound),
+		}
+		if len(itin.Inbound.Flights) > 0 {
+			jr.Tickets[i].Inbound = jsonFlights(itin.Inbound)
+		}
+	}
+
+	return jr
+}
+
+func toJSONPlan(plan search.BookingPlan) jsonPlan {
+	bookings := make([]jsonBooking, len(plan.Bookings))
+	for i, b := range plan.Bookings {
+		bookings[i] = jsonBooking{
+			Step:       i + 1,
+			Ticket:     b.Ticket + 1,
+			From:       b.Origin,
+			To:         b.Destination,
+			Departure:  b.Departure,
+			Price:      b.Price,
+			BookingURL: b.BookingURL,
+			Passengers: make([]jsonPassengers, len(b.Passengers)),
+		}
+		for j, f := range b.Passengers {
+			bookings[i].Passengers[j] = jsonPassengers{Type: f.Type, Count: f.Count, Total: f.Total}
+		}
+	}
+	return jsonPlan{Tickets: plan.Tickets, Bookings: bookings}
+}
+
+func jsonFlights(l leg.Leg) []jsonFlight {
+	flights := make([]jsonFlight, len(l.Flights))
+	for i, f := range l.Flights {
+		flights[i] = jsonFlight{
+			FlightCode:     f.FlightCode,
+			From:           f.DepartureAirport,
+			To:             f.ArrivalAirport,
+			Departure:      f.DepartureTime,
+			Arrival:        f.ArrivalTime,
+			Airline:        f.Airline,
+			AirlineCode:    f.AirlineCode,
+			OperatedBy:     f.OperatingAirline,
+			OperatedByCode: f.OperatingAirlineCode,
+			Plane:          f.Plane,
+			Aircraft:       f.Aircraft,
+			Cabin:          f.Cabin.String(),
+		}
+	}
+	return flights
+}
+
+// Text writes results for reading in a terminal, each ticket with its
+// flights and who operates any codeshares, then the order to book them in.
+func Text(w io.Writer, results []search.Result) error {
+	b := strings.Builder{}
+
+	for i, r := range results {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		fmt.Fprintf(&b, "via %s  %s", r.StopCity, r.Price)
+		if total, err := r.Total(); err == nil && total != r.Price {
+			fmt.Fprintf(&b, " (%s with bags and hotels)", total)
+		}
+		switch {
+		case r.SoldOut:
+			b.WriteString(", sold out")
+		case r.Verified:
+			fmt.Fprintf(&b, ", checked at %s", r.VerifiedAt.Format("15:04"))
+		case !r.ObservedAt.IsZero():
+			fmt.Fprintf(&b, ", priced at %s", r.ObservedAt.Format("15:04"))
+		}
+		b.WriteString("\n")
+
+		for j, itin := range r.Itineries {
+			fmt.Fprintf(&b, "  ticket %d  %s  %s\n", j+1, itin.Price, conditions(itin.Conditions))
+			writeFlights(&b, itin)
+		}
+		writePlan(&b, r.BookingPlan())
+
+		if r.CO2 > 0 {
+			fmt.Fprintf(&b, "  %.0f kg CO2e per passenger", r.CO2)
+			if r.DirectCO2 > 0 {
+				fmt.Fprintf(&b, ", %.0f kg flying direct", r.DirectCO2)
+			}
+			b.WriteString("\n")
+		}
+		for _, programme := range slices.Sorted(maps.Keys(r.Points)) {
+			fmt.Fprintf(&b, "  earns %d %s points and %d status credits\n",
+				r.Points[programme], programme, r.StatusCredits[programme])
+		}
+		for _, warning := range r.Warnings {
+			fmt.Fprintf(&b, "  ! %s\n", warning)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func conditions(c itinery.Conditions) string {
+	parts := make([]string, 0, 4)
+	if c.Brand != "" {
+		parts = append(parts, c.Brand)
+	}
+
+	switch {
+	case c.Estimated:
+		parts = append(parts, "fare rules unknown")
+	case c.Refundable:
+		parts = append(parts, "refundable")
+	case c.Changeable && !c.ChangeFee.IsZero():
+		parts = append(parts, fmt.Sprintf("changeable for %s", c.ChangeFee))
+	case c.Changeable:
+		parts = append(parts, "free changes")
+	default:
+		parts = append(parts, "non-refundable, no changes")
+	}
+
+	bags := "no cabin bag"
+	if c.CarryOn {
+		bags = "cabin bag"
+	}
+	if c.CheckedBags > 0 {
+		bags += fmt.Sprintf(" and %d checked", c.CheckedBags)
+	}
+	parts = append(parts, bags)
+
+	return strings.Join(parts, ", ")
+}
+
+func writePlan(b *strings.Builder, plan search.BookingPlan) {
+	if len(plan.Bookings) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "  book in this order, %s in tickets:\n", plan.Tickets)
+	for i, booking := range plan.Bookings {
+		fmt.Fprintf(b, "    %d. ticket %d %s-%s  %s", i+1, booking.Ticket+1, booking.Origin, booking.Destination, booking.Price)
+		for _, f := range booking.Passengers {
+			fmt.Fprintf(b, ", %d %s", f.Count, f.Type)
+		}
+		if booking.BookingURL != "" {
+			fmt.Fprintf(b, "  %s", booking.BookingURL)
+		}
+		b.WriteString("\n")
+	}
+}
+
+func writeFlights(b *strings.Builder, itin itinery.Itinery) {
+	for _, l := range []leg.Leg{itin.Outbound, itin.Inbound} {
+		for _, f := range l.Flights {
+			fmt.Fprintf(b, "    %s  %s → %s\n",
+				f, f.DepartureTime.Format("Mon 2 Jan 15:04"), f.ArrivalTime.Format("15:04"))
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"golang.org/x/text/currency"
+)
+
+var dep = time.Date(2030, 3, 1, 8, 0, 0, 0, time.UTC)
+
+func result() search.Result {
+	ticket := func(from, to, code string, operator leg.Flight) itinery.Itinery {
+		f := leg.Flight{
+			DepartureAirport:     from,
+			ArrivalAirport:       to,
+			DepartureTime:        dep,
+			ArrivalTime:          dep.Add(2 * time.Hour),
+			FlightCode:           code,
+			Airline:              operator.Airline,
+			AirlineCode:          operator.AirlineCode,
+			OperatingAirline:     operator.OperatingAirline,
+			OperatingAirlineCode: operator.OperatingAirlineCode,
+			Cabin:                leg.Economy,
+		}
+		return itinery.Itinery{
+			Outbound: leg.Leg{
+				Flights:          []leg.Flight{f},
+				DepartureAirport: from,
+				ArrivalAirport:   to,
+				DepartureTime:    dep,
+			},
+			Price:      money.New(100, currency.GBP),
+			BookingURL: "https://example.com/" + code,
+		}
+	}
+
+	r := search.Result{
+		StopCity: "MAD",
+		Price:    money.New(200, currency.GBP),
+		Itineries: []itinery.Itinery{
+			ticket("LHR", "MAD", "AA6132", leg.Flight{
+				Airline: "American Airlines", AirlineCode: "AA",
+				OperatingAirline: "Iberia", OperatingAirlineCode: "IB",
+			}),
+			ticket("MAD", "BOG", "IB6585", leg.Flight{Airline: "Iberia", AirlineCode: "IB"}),
+		},
+		Warnings: []string{"check the visa"},
+	}
+	r.Itineries[1].Price = money.New(140, currency.GBP)
+	r.Itineries[1].Breakdown = itinery.Split(r.Itineries[1].Price, map[itinery.PassengerType]int{itinery.Adult: 1})
+	r.Price = money.New(240, currency.GBP)
+	return r
+}
+
+func TestJSON(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := JSON(&buf, []search.Result{result()}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out []jsonResult
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 || len(out[0].Tickets) != 2 {
+		t.Fatalf("expected one result with 2 tickets, got %s", buf.String())
+	}
+
+	codeshare := out[0].Tickets[0].Outbound[0]
+	if codeshare.OperatedBy != "Iberia" || codeshare.OperatedByCode != "IB" {
+		t.Errorf("expected the codeshare to be operated by Iberia, got %+v", codeshare)
+	}
+	if own := out[0].Tickets[1].Outbound[0]; own.OperatedBy != "" {
+		t.Errorf("expected no operator for Iberia's own flight, got %q", own.OperatedBy)
+	}
+	if out[0].Total != money.New(240, currency.GBP) {
+		t.Errorf("expected a total of 240, got %s", out[0].Total)
+	}
+	plan := out[0].BookingPlan
+	if len(plan.Bookings) != 2 || plan.Bookings[0].Ticket != 2 || plan.Bookings[0].BookingURL != "https://example.com/IB6585" {
+		t.Errorf("expected the dearer second ticket to be booked first, got %+v", plan.Bookings)
+	}
+	if plan.Tickets != money.New(240, currency.GBP) {
+		t.Errorf("expected the plan's tickets to total 240, got %s", plan.Tickets)
+	}
+}
+
+func TestText(t *testing.T) {
+	r := result()
+	r.Verified, r.VerifiedAt = true, dep
+
+	buf := bytes.Buffer{}
+	if err := Text(&buf, []search.Result{r}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"via MAD  GBP 240.00, checked at 08:00",
+		"ticket 2  GBP 140.00  non-refundable, no changes, no cabin bag",
+		"AA6132 LHR-MAD operated by Iberia",
+		"IB6585 MAD-BOG  Fri 1 Mar 08:00",
+		"book in this order, GBP 240.00 in tickets:\n    1. ticket 2 MAD-BOG  GBP 140.00, 1 adult  https://example.com/IB6585\n    2. ticket 1 LHR-MAD",
+		"! check the visa",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %q in\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package fare
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/airport"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+)
+
+// Unknown is what a rule does with tickets whose conditions are estimated, as
+// whether they can be refunded or changed isn't known.
+type Unknown int
+
+const (
+	// keep the ticket and warn that it may not be flexible.
+	KeepUnknown Unknown = iota
+	// leave the ticket's result out.
+	DropUnknown
+)
+
+// Rule is what a ticket's conditions have to allow. It applies to tickets with
+// a flight at least MinKm long, so only the long-haul ticket of a trip can be
+// required to be flexible.
+type Rule struct {
+	MinKm float64
+
+	Refundable bool
+	Changeable bool
+	// either refundable or changeable.
+	Flexible bool
+	// the most a change can cost, zero for no limit.
+	MaxChangeFee money.Money
+	CarryOn      bool
+	CheckedBags  int
+
+	// applies when the rule asks for refunds or changes, estimated bags are
+	// checked like any others.
+	Unknown Unknown
+}
+
+// Applies reports whether the rule covers itin.
+func (rule Rule) Applies(itin itinery.Itinery) bool {
+	if rule.MinKm <= 0 {
+		return true
+	}
+	for _, l := range []leg.Leg{itin.Outbound, itin.Inbound} {
+		for _, f := range l.Flights {
+			if km, ok := airport.Distance(f.DepartureAirport, f.ArrivalAirport); ok && km >= rule.MinKm {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Allows reports whether c meets the rule. Estimated conditions meet its
+// refund and change rules unless it drops them, see Unknown.
+func (rule Rule) Allows(c itinery.Conditions) bool {
+	if c.Estimated && rule.flexibility() {
+		if rule.Unknown == DropUnknown {
+			return false
+		}
+		return rule.bags(c)
+	}
+
+	switch {
+	case rule.Refundable && !c.Refundable:
+		return false
+	case rule.Changeable && !c.Changeable:
+		return false
+	case rule.Flexible && !c.Flexible():
+		return false
+	case !rule.bags(c):
+		return false
+	}
+
+	if !rule.MaxChangeFee.IsZero() && !c.ChangeFee.IsZero() {
+		if cmp, err := c.ChangeFee.Cmp(rule.MaxChangeFee); err != nil || cmp > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// flexibility reports whether the rule asks anything of refunds or changes.
+func (rule Rule) flexibility() bool {
+	return rule.Refundable || rule.Changeable || rule.Flexible || !rule.MaxChangeFee.IsZero()
+}
+
+func (rule Rule) bags(c itinery.Conditions) bool {
+	return (!rule.CarryOn || c.CarryOn) && c.CheckedBags >= rule.CheckedBags
+}
+
+// Filter keeps the results whose tickets all meet the rule, where it applies.
+// Results kept with estimated tickets are warned that they may not meet it.
+func Filter(results []search.Result, rule Rule) []search.Result {
+	kept := make([]search.Result, 0, len(results))
+	for _, r := range results {
+		if !allows(r, rule) {
+			continue
+		}
+		if rule.flexibility() {
+			r = warn(r, rule)
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+func warn(r search.Result, rule Rule) search.Result {
+	warned := false
+	for i, itin := range r.Itineries {
+		if !itin.Conditions.Estimated || !rule.Applies(itin) {
+			continue
+		}
+		if !warned {
+			r.Warnings = slices.Clone(r.Warnings)
+			warned = true
+		}
+		r.Warnings = append(r.Warnings, fmt.Sprintf("ticket %d's fare rules aren't known, it may not be refundable or changeable", i+1))
+	}
+	return r
+}
+
+func allows(r search.Result, rule Rule) bool {
+	for _, itin := range r.Itineries {
+		if rule.Applies(itin) && !rule.Allows(itin.Conditions) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package fare
+
+import (
+	"testing"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"golang.org/x/text/currency"
+)
+
+func ticket(from, to string, c itinery.Conditions) itinery.Itinery {
+	return itinery.Itinery{
+		Outbound:   leg.Leg{Flights: []leg.Flight{{DepartureAirport: from, ArrivalAirport: to}}},
+		Conditions: c,
+	}
+}
+
+func TestFilter_LongHaulOnly(t *testing.T) {
+	flexible := itinery.Conditions{Changeable: true, ChangeFee: money.New(50, currency.GBP), CarryOn: true}
+	light := itinery.Conditions{CarryOn: true}
+
+	results := []search.Result{
+		{StopCity: "DOH", Itineries: []itinery.Itinery{ticket("MAN", "LHR", light), ticket("LHR", "BKK", flexible)}},
+		{StopCity: "DOH", Itineries: []itinery.Itinery{ticket("MAN", "LHR", flexible), ticket("LHR", "BKK", light)}},
+		{StopCity: "DOH", Itineries: []itinery.Itinery{ticket("MAN", "LHR", light), ticket("LHR", "BKK", itinery.Conditions{Estimated: true})}},
+	}
+
+	kept := Filter(results, Rule{MinKm: 4000, Flexible: true, Unknown: DropUnknown})
+	if len(kept) != 1 || kept[0].Itineries[1].Conditions != flexible {
+		t.Errorf("expected only the result with a flexible long-haul ticket, got %d", len(kept))
+	}
+
+	kept = Filter(results, Rule{MinKm: 4000, Flexible: true})
+	if len(kept) != 2 {
+		t.Fatalf("expected the estimated long-haul ticket to be kept, got %d", len(kept))
+	}
+	if len(kept[0].Warnings) != 0 || len(kept[1].Warnings) != 1 {
+		t.Errorf("expected only the estimated ticket to be warned about, got %v and %v", kept[0].Warnings, kept[1].Warnings)
+	}
+	if len(results[2].Warnings) != 0 {
+		t.Errorf("expected the given results not to be changed, got %v", results[2].Warnings)
+	}
+
+	kept = Filter(results, Rule{MinKm: 4000, Changeable: true, MaxChangeFee: money.New(40, currency.GBP), Unknown: DropUnknown})
+	if len(kept) != 0 {
+		t.Errorf("expected a 50 change fee to be over the limit, got %d results", len(kept))
+	}
+}
+
+func TestRuleAllows(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		c    itinery.Conditions
+		want bool
+	}{
+		{"no rule", Rule{}, itinery.Conditions{}, true},
+		{"refundable", Rule{Refundable: true}, itinery.Conditions{Changeable: true}, false},
+		{"free changes", Rule{Changeable: true, MaxChangeFee: money.New(10, currency.GBP)}, itinery.Conditions{Changeable: true}, true},
+		{"checked bag", Rule{CheckedBags: 1}, itinery.Conditions{CarryOn: true}, false},
+		{"estimated bags", Rule{CarryOn: true}, itinery.Conditions{CarryOn: true, Estimated: true}, true},
+		{"estimated flexibility", Rule{Flexible: true}, itinery.Conditions{CarryOn: true, Estimated: true}, true},
+		{"estimated flexibility dropped", Rule{Flexible: true, Unknown: DropUnknown}, itinery.Conditions{Estimated: true}, false},
+		{"estimated bags missing", Rule{Flexible: true, CheckedBags: 1}, itinery.Conditions{Estimated: true}, false},
+		{"fee in another currency", Rule{Changeable: true, MaxChangeFee: money.New(100, currency.GBP)}, itinery.Conditions{Changeable: true, ChangeFee: money.New(50, currency.EUR)}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.rule.Allows(tt.c); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"golang.org/x/text/currency"
+)
+
+var ErrNoRate = errors.New("no exchange rate")
+
+// RateSource gives the rate to multiply an amount in from by to get it in to,
+// as it was on the given date.
+type RateSource interface {
+	Rate(ctx context.Context, from, to currency.Unit, date time.Time) (float64, error)
+}
+
+type Converter struct {
+	Source RateSource
+}
+
+func NewConverter(src RateSource) *Converter {
+	return &Converter{Source: src}
+}
+
+// Convert returns m in to at the rate on date. Amounts already in to are
+// returned as they are.
+func (c *Converter) Convert(
+	ctx context.Context,
+	m money.Money,
+	to currency.Unit,
+	date time.Time,
+) (money.Money, error) {
+	if m.Currency == to {
+		return m, nil
+	}
+	if m.IsZero() {
+		return money.Zero(to), nil
+	}
+
+	rate, err := c.Source.Rate(ctx, m.Currency, to, date)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("convert %s to %s: %w", m.Currency, to, err)
+	}
+
+	return money.New(m.Float64()*rate, to), nil
+}
+
+// Itineries returns copies of itins priced in to.
+func (c *Converter) Itineries(
+	ctx context.Context,
+	itins []itinery.Itinery,
+	to currency.Unit,
+	date time.Time,
+) ([]itinery.Itinery, error) {
+	converted := make([]itinery.Itinery, len(itins))
+	for i, itin := range itins {
+		price, err := c.Convert(ctx, itin.Price, to, date)
+		if err != nil {
+			return nil, err
+		}
+		itin.Price = price
+		// left unset when there's no fee, so conditions without rules stay zero.
+		if !itin.Conditions.ChangeFee.IsZero() {
+			if itin.Conditions.ChangeFee, err = c.Convert(ctx, itin.Conditions.ChangeFee, to, date); err != nil {
+				return nil, err
+			}
+		}
+
+		fares := make([]itinery.Fare, len(itin.Breakdown.Fares))
+		for j, f := range itin.Breakdown.Fares {
+			for _, m := range []*money.Money{&f.Base, &f.Taxes, &f.Total} {
+				if *m, err = c.Convert(ctx, *m, to, date); err != nil {
+					return nil, err
+				}
+			}
+			fares[j] = f
+		}
+		itin.Breakdown.Fares = fares
+
+		converted[i] = itin
+	}
+	return converted, nil
+}
+
+// Result returns r with every ticket priced in to and the total recomputed.
+func (c *Converter) Result(
+	ctx context.Context,
+	r search.Result,
+	to currency.Unit,
+	date time.Time,
+) (search.Result, error) {
+	itins, err := c.Itineries(ctx, r.Itineries, to, date)
+	if err != nil {
+		return search.Result{}, err
+	}
+
+	prices := make([]money.Money, len(itins))
+	for i, itin := range itins {
+		prices[i] = itin.Price
+	}
+
+	r.Itineries = itins
+	if r.Price, err = money.Sum(prices...); err != nil {
+		return search.Result{}, err
+	}
+	if r.Price.Currency != to {
+		r.Price = money.Zero(to)
+	}
+
+	return r, nil
+}
+
+// day is the date rates are keyed on, ignoring the time and location.
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,158 @@
+package fx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"golang.org/x/text/currency"
+)
+
+const ratesCSV = `date,from,to,rate
+2030-01-01,EUR,GBP,0.85
+2030-01-03,EUR,GBP,0.9
+2030-01-01,USD,GBP,0.8
+`
+
+var (
+	jan2 = time.Date(2030, 1, 2, 15, 0, 0, 0, time.UTC)
+	jan3 = time.Date(2030, 1, 3, 0, 0, 0, 0, time.UTC)
+)
+
+func static(t *testing.T) *Static {
+	t.Helper()
+	s, err := LoadStatic(strings.NewReader(ratesCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestStatic_Rate(t *testing.T) {
+	s := static(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		from, to currency.Unit
+		date     time.Time
+		want     float64
+	}{
+		{"latest before date", currency.EUR, currency.GBP, jan2, 0.85},
+		{"on date", currency.EUR, currency.GBP, jan3, 0.9},
+		{"inverse", currency.GBP, currency.USD, jan2, 1.25},
+		{"same currency", currency.JPY, currency.JPY, jan2, 1},
+	}
+
+	for _, tt := range tests {
+		got, err := s.Rate(ctx, tt.from, tt.to, tt.date)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	if _, err := s.Rate(ctx, currency.EUR, currency.GBP, jan2.AddDate(0, 0, -5)); !errors.Is(err, ErrNoRate) {
+		t.Errorf("expected ErrNoRate before the first rate, got %v", err)
+	}
+}
+
+func TestConverter_Result(t *testing.T) {
+	c := NewConverter(static(t))
+
+	r, err := search.NewResult(
+		itinery.Itinery{Price: money.New(100, currency.EUR)},
+		itinery.Itinery{Price: money.New(50, currency.EUR)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Itineries[1].Price = money.New(50, currency.GBP)
+	r.Itineries[0].Conditions.ChangeFee = money.New(20, currency.EUR)
+
+	r, err = c.Result(context.Background(), r, currency.GBP, jan2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Itineries[0].Price != money.New(85, currency.GBP) {
+		t.Errorf("expected first ticket to be GBP 85, got %s", r.Itineries[0].Price)
+	}
+	if r.Price != money.New(135, currency.GBP) {
+		t.Errorf("expected total to be recomputed as GBP 135, got %s", r.Price)
+	}
+	if r.Itineries[0].Conditions.ChangeFee != money.New(17, currency.GBP) {
+		t.Errorf("expected the change fee to be GBP 17, got %s", r.Itineries[0].Conditions.ChangeFee)
+	}
+	if r.Itineries[1].Conditions != (itinery.Conditions{}) {
+		t.Errorf("expected a ticket without rules to be left without, got %+v", r.Itineries[1].Conditions)
+	}
+}
+
+func TestSnapshot_SaveAndLoad(t *testing.T) {
+	ctx := context.Background()
+	s := NewSnapshot(static(t))
+
+	if _, err := s.Rate(ctx, currency.EUR, currency.GBP, jan2); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := s.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadSnapshot(&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rate, err := loaded.Rate(ctx, currency.EUR, currency.GBP, jan2)
+	if err != nil || rate != 0.85 {
+		t.Errorf("expected saved rate of 0.85, got %v (%v)", rate, err)
+	}
+	if _, err := loaded.Rate(ctx, currency.USD, currency.GBP, jan2); !errors.Is(err, ErrNoRate) {
+		t.Errorf("expected ErrNoRate for a rate never fetched, got %v", err)
+	}
+}
+
+func TestHTTP_Rate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/2030-01-02" || r.URL.Query().Get("from") != "EUR" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(httpRates{
+			Date:  "2030-01-02",
+			From:  "EUR",
+			Rates: map[string]float64{"GBP": 0.86},
+		})
+	}))
+	defer srv.Close()
+
+	h := NewHTTP(srv.URL)
+
+	rate, err := h.Rate(context.Background(), currency.EUR, currency.GBP, jan2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate != 0.86 {
+		t.Errorf("expected 0.86, got %v", rate)
+	}
+
+	if _, err := h.Rate(context.Background(), currency.USD, currency.GBP, jan2); !errors.Is(err, ErrNoRate) {
+		t.Errorf("expected ErrNoRate for an unknown pair, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/text/currency"
+)
+
+// HTTP fetches rates from a rates service, requesting
+//
+//	GET {URL}/{YYYY-MM-DD}?from=GBP&to=EUR
+//
+// and expecting a body like {"date":"2030-01-01","from":"GBP","rates":{"EUR":1.17}}.
+type HTTP struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewHTTP(url string) *HTTP {
+	return &HTTP{
+		URL:    url,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type httpRates struct {
+	Date  string             `json:"date"`
+	From  string             `json:"from"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+func (h *HTTP) Rate(ctx context.Context, from, to currency.Unit, date time.Time) (float64, error) {
+	q := url.Values{}
+	q.Set("from", from.String())
+	q.Set("to", to.String())
+	u := h.URL + "/" + date.Format(time.DateOnly) + "?" + q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := h.Client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("fetch rates: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return 0, fmt.Errorf("%w: %s to %s on %s", ErrNoRate, from, to, date.Format(time.DateOnly))
+	}
+	if res.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("fetch rates: unexpected status %s", res.Status)
+	}
+
+	var body httpRates
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("decode rates: %w", err)
+	}
+
+	rate, ok := body.Rates[to.String()]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s to %s on %s", ErrNoRate, from, to, date.Format(time.DateOnly))
+	}
+	return rate, nil
+}
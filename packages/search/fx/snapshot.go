@@ -0,0 +1,101 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/text/currency"
+)
+
+type snapshotKey struct {
+	pair
+	date time.Time
+}
+
+// Snapshot remembers every rate fetched from its source so a search only asks
+// for each rate once, and can be saved to replay the same rates later without
+// the source.
+type Snapshot struct {
+	source RateSource
+
+	mu    sync.Mutex
+	rates map[snapshotKey]float64
+}
+
+// NewSnapshot wraps source, which may be nil to only serve saved rates.
+func NewSnapshot(source RateSource) *Snapshot {
+	return &Snapshot{
+		source: source,
+		rates:  make(map[snapshotKey]float64),
+	}
+}
+
+// LoadSnapshot reads rates saved with Save, falling back to source for any
+// that weren't saved.
+func LoadSnapshot(r io.Reader, source RateSource) (*Snapshot, error) {
+	s := NewSnapshot(source)
+	err := readRates(r, func(from, to currency.Unit, date time.Time, rate float64) {
+		s.rates[snapshotKey{pair{from, to}, day(date)}] = rate
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Snapshot) Rate(ctx context.Context, from, to currency.Unit, date time.Time) (float64, error) {
+	k := snapshotKey{pair{from, to}, day(date)}
+
+	s.mu.Lock()
+	rate, ok := s.rates[k]
+	s.mu.Unlock()
+	if ok {
+		return rate, nil
+	}
+
+	if s.source == nil {
+		return 0, fmt.Errorf("%w: %s to %s on %s", ErrNoRate, from, to, date.Format(time.DateOnly))
+	}
+
+	rate, err := s.source.Rate(ctx, from, to, date)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	s.rates[k] = rate
+	s.mu.Unlock()
+
+	return rate, nil
+}
+
+// Save writes every rate seen so far in the csv format LoadStatic reads.
+func (s *Snapshot) Save(w io.Writer) error {
+	s.mu.Lock()
+	rows := make([][]string, 0, len(s.rates))
+	for k, rate := range s.rates {
+		rows = append(rows, []string{
+			k.date.Format(time.DateOnly),
+			k.from.String(),
+			k.to.String(),
+			strconv.FormatFloat(rate, 'f', -1, 64),
+		})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(rows, func(i, j int) bool {
+		for c := range rows[i] {
+			if rows[i][c] != rows[j][c] {
+				return rows[i][c] < rows[j][c]
+			}
+		}
+		return false
+	})
+
+	return writeRates(w, rows)
+}
@@ -0,0 +1,139 @@
+package fx
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"golang.org/x/text/currency"
+)
+
+type pair struct {
+	from, to currency.Unit
+}
+
+type dated struct {
+	date time.Time
+	rate float64
+}
+
+// Static serves rates from a fixed table, such as a file of published daily
+// rates. A date without a rate uses the latest rate before it, so weekends and
+// holidays fall back to the last working day.
+type Static struct {
+	rates map[pair][]dated
+}
+
+func NewStatic() *Static {
+	return &Static{rates: make(map[pair][]dated)}
+}
+
+// LoadStatic reads a csv of date,from,to,rate rows with a header, dates in
+// YYYY-MM-DD format.
+func LoadStatic(r io.Reader) (*Static, error) {
+	s := NewStatic()
+	if err := readRates(r, s.Set); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Set adds or replaces the rate between from and to on date.
+func (s *Static) Set(from, to currency.Unit, date time.Time, rate float64) {
+	p := pair{from, to}
+	date = day(date)
+
+	rates := s.rates[p]
+	i := sort.Search(len(rates), func(i int) bool {
+		return !rates[i].date.Before(date)
+	})
+	if i < len(rates) && rates[i].date.Equal(date) {
+		rates[i].rate = rate
+		return
+	}
+	rates = append(rates, dated{})
+	copy(rates[i+1:], rates[i:])
+	rates[i] = dated{date: date, rate: rate}
+	s.rates[p] = rates
+}
+
+// Rate uses the inverse of the opposite pair when only that is known.
+func (s *Static) Rate(_ context.Context, from, to currency.Unit, date time.Time) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	if rate, ok := s.lookup(pair{from, to}, date); ok {
+		return rate, nil
+	}
+	if rate, ok := s.lookup(pair{to, from}, date); ok && rate != 0 {
+		return 1 / rate, nil
+	}
+	return 0, fmt.Errorf("%w: %s to %s on %s", ErrNoRate, from, to, date.Format(time.DateOnly))
+}
+
+func (s *Static) lookup(p pair, date time.Time) (float64, bool) {
+	rates := s.rates[p]
+	date = day(date)
+
+	// the first rate after date, the one before it is the latest that applies.
+	i := sort.Search(len(rates), func(i int) bool {
+		return rates[i].date.After(date)
+	})
+	if i == 0 {
+		return 0, false
+	}
+	return rates[i-1].rate, true
+}
+
+func readRates(r io.Reader, set func(from, to currency.Unit, date time.Time, rate float64)) error {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return err
+	}
+
+	for i, rec := range records {
+		// skip the header
+		if i == 0 {
+			continue
+		}
+		if len(rec) != 4 {
+			return fmt.Errorf("line %d: expected 4 fields, got %d", i+1, len(rec))
+		}
+
+		date, err := time.Parse(time.DateOnly, rec[0])
+		if err != nil {
+			return fmt.Errorf("line %d: invalid date: %w", i+1, err)
+		}
+		from, err := currency.ParseISO(rec[1])
+		if err != nil {
+			return fmt.Errorf("line %d: invalid currency: %w", i+1, err)
+		}
+		to, err := currency.ParseISO(rec[2])
+		if err != nil {
+			return fmt.Errorf("line %d: invalid currency: %w", i+1, err)
+		}
+		rate, err := strconv.ParseFloat(rec[3], 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid rate: %w", i+1, err)
+		}
+
+		set(from, to, date, rate)
+	}
+
+	return nil
+}
+
+func writeRates(w io.Writer, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"date", "from", "to", "rate"}); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
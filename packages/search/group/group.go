@@ -0,0 +1,418 @@
+package group
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/airport"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"github.com/tobyrushton/flyvia/packages/search/provider"
+	"golang.org/x/text/currency"
+)
+
+const defaultMaxCandidates = 5
+
+// Origin is a part of the group travelling together from the same place.
+type Origin struct {
+	Origin string
+	provider.Party
+}
+
+type Request struct {
+	Origins []Origin
+
+	DepartureDate time.Time
+	ReturnDate    time.Time
+
+	// destinations to consider, when empty they come from the explore results
+	// every origin can fly to.
+	Candidates []string
+	// number of candidates confirmed with searches.
+	MaxCandidates int
+	// what an hour between the first and last arrival is worth when ranking,
+	// zero ranks on cost alone. Must be in Currency.
+	SpreadCost money.Money
+
+	Currency currency.Unit
+	Class    provider.Class
+}
+
+type OriginItinery struct {
+	Origin  string
+	Itinery itinery.Itinery
+	// the meetup is in the origin's own city, there's no ticket to buy.
+	Home bool
+}
+
+type Meetup struct {
+	Destination string
+	City        string
+	Country     string
+
+	// in the same order as Request.Origins.
+	Itineries []OriginItinery
+	Total     money.Money
+	// time between the first and last of the group arriving.
+	Spread time.Duration
+	// Total plus the spread at Request.SpreadCost, what meetups are ranked on.
+	Score money.Money
+}
+
+type Result struct {
+	// best first.
+	Meetups []Meetup
+	// destinations left out because a search for them failed.
+	Warnings []string
+}
+
+type candidate struct {
+	destination string
+	city        string
+	country     string
+	estimate    money.Money
+	// airports the origins can fly to in the city.
+	airports map[string]bool
+	// number of origins that can get there.
+	reached int
+}
+
+// Search finds destinations every origin can fly to, ranked by what it costs
+// the whole group to get there and how far apart everyone arrives. A
+// destination whose search fails is left out with a warning, an error is
+// only returned when every one does.
+func Search(
+	ctx context.Context,
+	p provider.Provider,
+	req Request,
+) (*Result, error) {
+	if len(req.Origins) < 2 {
+		return nil, fmt.Errorf("a group search needs at least two origins")
+	}
+	for _, o := range req.Origins {
+		if err := o.Validate(); err != nil {
+			return nil, fmt.Errorf("origin %s: %w", o.Origin, err)
+		}
+	}
+	if !req.SpreadCost.IsZero() && req.SpreadCost.Currency != req.Currency {
+		return nil, fmt.Errorf("%w: spread cost in %s", money.ErrCurrencyMismatch, req.SpreadCost.Currency)
+	}
+
+	candidates, err := explore(ctx, p, req)
+	if err != nil {
+		return nil, err
+	}
+
+	maxCandidates := req.MaxCandidates
+	if maxCandidates <= 0 {
+		maxCandidates = defaultMaxCandidates
+	}
+	if len(candidates) > maxCandidates {
+		candidates = candidates[:maxCandidates]
+	}
+
+	res := &Result{Meetups: make([]Meetup, 0, len(candidates))}
+	var firstErr error
+	for _, c := range candidates {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		perOrigin, home, err := searchOrigins(ctx, p, req, c)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			res.Warnings = append(res.Warnings, fmt.Sprintf("%s left out, its search failed: %v", c.destination, err))
+			continue
+		}
+
+		m, ok := meet(req, perOrigin, home)
+		if !ok {
+			continue
+		}
+		m.Destination = c.destination
+		m.City = c.city
+		m.Country = c.country
+		res.Meetups = append(res.Meetups, m)
+	}
+	if firstErr != nil && len(res.Warnings) == len(candidates) {
+		return nil, firstErr
+	}
+
+	// sorts are stable, so meetups scoring the same stay ordered by total.
+	if err := money.Sort(res.Meetups, func(m Meetup) money.Money { return m.Total }); err != nil {
+		return nil, err
+	}
+	if err := money.Sort(res.Meetups, func(m Meetup) money.Money { return m.Score }); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// searchOrigins searches every origin's way to the candidate, origins already
+// there get a single empty itinery.
+func searchOrigins(
+	ctx context.Context,
+	p provider.Provider,
+	req Request,
+	c candidate,
+) ([][]itinery.Itinery, []bool, error) {
+	perOrigin := make([][]itinery.Itinery, len(req.Origins))
+	home := make([]bool, len(req.Origins))
+	for i, o := range req.Origins {
+		if o.in(c.city) {
+			perOrigin[i], home[i] = []itinery.Itinery{{}}, true
+			continue
+		}
+		itins, err := p.Search(ctx, o.request(req, c.destination))
+		if err != nil {
+			return nil, nil, fmt.Errorf("from %s: %w", o.Origin, err)
+		}
+		perOrigin[i] = itins
+	}
+	return perOrigin, home, nil
+}
+
+func (o Origin) request(req Request, destination string) provider.Request {
+	return provider.Request{
+		Origin:        o.Origin,
+		Destination:   destination,
+		DepartureDate: req.DepartureDate,
+		ReturnDate:    req.ReturnDate,
+		Party:         o.Party,
+		Currency:      req.Currency,
+		Class:         req.Class,
+	}
+}
+
+// city is where the origin is, origins are either a city or an airport.
+func (o Origin) city() string {
+	if a, ok := airport.Lookup(o.Origin); ok {
+		return a.City
+	}
+	return o.Origin
+}
+
+func (o Origin) in(city string) bool {
+	return city != "" && strings.EqualFold(o.city(), city)
+}
+
+// place is the city an explore result goes to, so origins flying to different
+// airports in the same city still meet.
+func place(ei itinery.ExploreItinery) string {
+	if ei.City != "" {
+		return ei.City
+	}
+	if a, ok := airport.Lookup(ei.Destination); ok && a.City != "" {
+		return a.City
+	}
+	return ei.Destination
+}
+
+// explore returns the cities every origin can reach, including the home of
+// any origin the rest can fly to, cheapest for the group first. A city is
+// searched by its airport when everyone flies to the same one. Given
+// candidates are kept in order as there's nothing to estimate them on.
+func explore(ctx context.Context, p provider.Provider, req Request) ([]candidate, error) {
+	if len(req.Candidates) > 0 {
+		candidates := make([]candidate, len(req.Candidates))
+		for i, dest := range req.Candidates {
+			candidates[i] = candidate{destination: dest}
+		}
+		return candidates, nil
+	}
+
+	results := make([][]itinery.ExploreItinery, len(req.Origins))
+	errs := make([]error, len(req.Origins))
+	wg := sync.WaitGroup{}
+
+	for i, o := range req.Origins {
+		wg.Add(1)
+		go func(i int, o Origin) {
+			defer wg.Done()
+
+			explore := provider.ExploreRequest{
+				Origins:       []string{o.Origin},
+				DepartureFrom: req.DepartureDate,
+				Party:         o.Party,
+				Currency:      req.Currency,
+				Class:         req.Class,
+			}
+			if !req.ReturnDate.IsZero() {
+				days := provider.Days(req.DepartureDate, req.ReturnDate)
+				explore.MinTripLength = days
+				explore.MaxTripLength = days
+			}
+
+			results[i], errs[i] = p.Explore(ctx, explore)
+		}(i, o)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reachable := make(map[string]*candidate)
+	for i, eis := range results {
+		// the cheapest way for this origin to each city.
+		cheapest := make(map[string]itinery.ExploreItinery)
+		for _, ei := range eis {
+			k := strings.ToLower(place(ei))
+			if req.Origins[i].in(k) {
+				continue
+			}
+			if c, ok := cheapest[k]; ok {
+				if cmp, err := ei.Price.Cmp(c.Price); err != nil || cmp >= 0 {
+					continue
+				}
+			}
+			cheapest[k] = ei
+		}
+
+		for k, ei := range cheapest {
+			c, ok := reachable[k]
+			if !ok {
+				c = &candidate{city: place(ei), country: ei.Country, airports: make(map[string]bool)}
+				reachable[k] = c
+			}
+			estimate, err := c.estimate.Add(ei.Price)
+			if err != nil {
+				continue
+			}
+			c.estimate = estimate
+			c.airports[ei.Destination] = true
+			c.reached++
+		}
+	}
+
+	// origins are already in their own city.
+	for _, o := range req.Origins {
+		if c, ok := reachable[strings.ToLower(o.city())]; ok {
+			c.reached++
+		}
+	}
+
+	candidates := make([]candidate, 0)
+	for _, c := range reachable {
+		if c.reached < len(req.Origins) {
+			continue
+		}
+		c.destination = c.city
+		if len(c.airports) == 1 {
+			for a := range c.airports {
+				c.destination = a
+			}
+		}
+		candidates = append(candidates, *c)
+	}
+
+	if err := money.Sort(candidates, func(c candidate) money.Money { return c.estimate }); err != nil {
+		return nil, err
+	}
+
+	return candidates, nil
+}
+
+// meet picks an itinery for every origin. Each arrival is tried as the time
+// everyone aims for, picking per origin what is cheapest once the distance
+// from it is costed in, and the best scoring combination wins. Origins at
+// home have a single empty itinery and no arrival.
+func meet(req Request, perOrigin [][]itinery.Itinery, home []bool) (Meetup, bool) {
+	for _, itins := range perOrigin {
+		if len(itins) == 0 {
+			return Meetup{}, false
+		}
+	}
+
+	var best Meetup
+	found := false
+
+	for i, itins := range perOrigin {
+		if home[i] {
+			continue
+		}
+		for _, anchor := range itins {
+			target := anchor.Outbound.ArrivalTime
+
+			m := Meetup{Itineries: make([]OriginItinery, len(perOrigin))}
+			for i, options := range perOrigin {
+				choice := options[0]
+				for _, itin := range options[1:] {
+					if c, err := cost(req, itin, target).Cmp(cost(req, choice, target)); err == nil && c < 0 {
+						choice = itin
+					}
+				}
+				m.Itineries[i] = OriginItinery{Origin: req.Origins[i].Origin, Itinery: choice, Home: home[i]}
+			}
+
+			var err error
+			if m.Total, err = total(m.Itineries); err != nil {
+				continue
+			}
+
+			m.Spread = spread(m.Itineries)
+			if m.Score, err = m.Total.Add(req.SpreadCost.Mul(m.Spread.Hours())); err != nil {
+				continue
+			}
+
+			if found {
+				if c, err := m.Score.Cmp(best.Score); err != nil || c >= 0 {
+					continue
+				}
+			}
+			best, found = m, true
+		}
+	}
+
+	return best, found
+}
+
+// cost is what an itinery is worth when aiming to arrive at target, a
+// currency mismatch leaves the arrival time uncosted.
+func cost(req Request, itin itinery.Itinery, target time.Time) money.Money {
+	hours := math.Abs(itin.Outbound.ArrivalTime.Sub(target).Hours())
+	c, err := itin.Price.Add(req.SpreadCost.Mul(hours))
+	if err != nil {
+		return itin.Price
+	}
+	return c
+}
+
+func total(itins []OriginItinery) (money.Money, error) {
+	prices := make([]money.Money, len(itins))
+	for i, oi := range itins {
+		prices[i] = oi.Itinery.Price
+	}
+	return money.Sum(prices...)
+}
+
+func spread(itins []OriginItinery) time.Duration {
+	var first, last time.Time
+	for _, oi := range itins {
+		if oi.Home {
+			continue
+		}
+		arr := oi.Itinery.Outbound.ArrivalTime
+		if first.IsZero() {
+			first, last = arr, arr
+			continue
+		}
+		if arr.Before(first) {
+			first = arr
+		}
+		if arr.After(last) {
+			last = arr
+		}
+	}
+	return last.Sub(first)
+}
@@ -0,0 +1,276 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money/moneytest"
+	"github.com/tobyrushton/flyvia/packages/search/provider"
+	"github.com/tobyrushton/flyvia/packages/search/provider/providertest"
+	"golang.org/x/text/currency"
+)
+
+var friday = time.Date(2030, 5, 3, 0, 0, 0, 0, time.UTC)
+
+type fare struct {
+	price   float64
+	arrival time.Duration // after midnight on friday
+}
+
+type fakeFlights struct {
+	explores map[string][]string
+	fares    map[[2]string][]fare
+	adults   map[string]int
+	// destination whose searches fail.
+	failing string
+}
+
+func (f *fakeFlights) explore(
+	_ context.Context,
+	req provider.ExploreRequest,
+) ([]itinery.ExploreItinery, error) {
+	eis := make([]itinery.ExploreItinery, 0)
+	for _, dest := range f.explores[req.Origins[0]] {
+		fares := f.fares[[2]string{req.Origins[0], dest}]
+		eis = append(eis, itinery.ExploreItinery{Destination: dest, Price: moneytest.GBP(fares[0].price)})
+	}
+	return eis, nil
+}
+
+func (f *fakeFlights) search(
+	_ context.Context,
+	req provider.Request,
+) ([]itinery.Itinery, error) {
+	f.adults[req.Origin] = req.Adults
+	if req.Destination == f.failing {
+		return nil, errors.New("search failed")
+	}
+
+	itins := make([]itinery.Itinery, 0)
+	for _, fr := range f.fares[[2]string{req.Origin, req.Destination}] {
+		itins = append(itins, itinery.Itinery{
+			Outbound: leg.Leg{
+				DepartureAirport: req.Origin,
+				ArrivalAirport:   req.Destination,
+				ArrivalTime:      friday.Add(fr.arrival),
+			},
+			Price: moneytest.GBP(fr.price),
+		})
+	}
+	return itins, nil
+}
+
+func (f *fakeFlights) provider() *providertest.Provider {
+	return &providertest.Provider{ExploreFunc: f.explore, SearchFunc: f.search}
+}
+
+func newFakeFlights() *fakeFlights {
+	return &fakeFlights{
+		explores: map[string][]string{
+			"Berlin":  {"LIS", "AMS", "PRG"},
+			"Madrid":  {"LIS", "AMS", "PRG"},
+			"Glasgow": {"LIS", "AMS"},
+		},
+		fares: map[[2]string][]fare{
+			{"Berlin", "LIS"}:  {{100, 18 * time.Hour}},
+			{"Madrid", "LIS"}:  {{60, 19 * time.Hour}, {40, 8 * time.Hour}},
+			{"Glasgow", "LIS"}: {{120, 20 * time.Hour}},
+			{"Berlin", "AMS"}:  {{70, 9 * time.Hour}},
+			{"Madrid", "AMS"}:  {{90, 10 * time.Hour}},
+			{"Glasgow", "AMS"}: {{80, 23 * time.Hour}},
+			{"Berlin", "PRG"}:  {{30, 9 * time.Hour}},
+			{"Madrid", "PRG"}:  {{80, 9 * time.Hour}},
+		},
+		adults: make(map[string]int),
+	}
+}
+
+func request(spreadCost float64) Request {
+	return Request{
+		Origins: []Origin{
+			{Origin: "Berlin", Party: provider.Party{Adults: 2}},
+			{Origin: "Madrid", Party: provider.Party{Adults: 1}},
+			{Origin: "Glasgow", Party: provider.Party{Adults: 3}},
+		},
+		DepartureDate: friday,
+		ReturnDate:    friday.AddDate(0, 0, 2),
+		SpreadCost:    moneytest.GBP(spreadCost),
+		Currency:      currency.GBP,
+	}
+}
+
+func TestSearch_RanksOnCost(t *testing.T) {
+	p := newFakeFlights()
+
+	res, err := Search(context.Background(), p.provider(), request(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	meetups := res.Meetups
+
+	if len(meetups) != 2 {
+		t.Fatalf("expected 2 destinations everyone can reach, got %d", len(meetups))
+	}
+
+	if meetups[0].Destination != "AMS" || meetups[0].Total != moneytest.GBP(240) {
+		t.Errorf("expected AMS at 240 to be cheapest, got %s at %s", meetups[0].Destination, meetups[0].Total)
+	}
+	if meetups[1].Total != moneytest.GBP(260) {
+		t.Errorf("expected LIS to use the cheapest Madrid flight for 260, got %s", meetups[1].Total)
+	}
+
+	if len(meetups[0].Itineries) != 3 || meetups[0].Itineries[2].Origin != "Glasgow" {
+		t.Error("expected an itinery per origin in request order")
+	}
+	if p.adults["Glasgow"] != 3 {
+		t.Errorf("expected Glasgow to search for 3 adults, got %d", p.adults["Glasgow"])
+	}
+}
+
+func TestSearch_RanksOnSpread(t *testing.T) {
+	res, err := Search(context.Background(), newFakeFlights().provider(), request(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	meetups := res.Meetups
+
+	// everyone reaches LIS between 18:00 and 20:00 for 280, AMS arrivals are
+	// 14 hours apart.
+	if meetups[0].Destination != "LIS" {
+		t.Fatalf("expected LIS to win on spread, got %s", meetups[0].Destination)
+	}
+	if meetups[0].Spread != 2*time.Hour {
+		t.Errorf("expected 2 hour spread, got %v", meetups[0].Spread)
+	}
+	if meetups[0].Total != moneytest.GBP(280) {
+		t.Errorf("expected the later Madrid flight to be chosen for 280, got %s", meetups[0].Total)
+	}
+}
+
+func TestSearch_NeedsTwoOrigins(t *testing.T) {
+	_, err := Search(context.Background(), newFakeFlights().provider(), Request{
+		Origins: []Origin{{Origin: "Berlin", Party: provider.Party{Adults: 1}}},
+	})
+	if err == nil {
+		t.Error("expected an error for a single origin")
+	}
+}
+
+func TestSearch_MeetsInCity(t *testing.T) {
+	p := &fakeFlights{
+		explores: map[string][]string{
+			"Berlin": {"LHR"},
+			"Madrid": {"LGW"},
+		},
+		fares: map[[2]string][]fare{
+			{"Berlin", "LHR"}:    {{100, 10 * time.Hour}},
+			{"Madrid", "LGW"}:    {{80, 11 * time.Hour}},
+			{"Berlin", "London"}: {{100, 10 * time.Hour}},
+			{"Madrid", "London"}: {{80, 11 * time.Hour}},
+		},
+		adults: make(map[string]int),
+	}
+
+	res, err := Search(context.Background(), p.provider(), Request{
+		Origins:       []Origin{{Origin: "Berlin", Party: provider.Party{Adults: 1}}, {Origin: "Madrid", Party: provider.Party{Adults: 1}}},
+		DepartureDate: friday,
+		Currency:      currency.GBP,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meetups := res.Meetups
+
+	if len(meetups) != 1 || meetups[0].Destination != "London" {
+		t.Fatalf("expected Heathrow and Gatwick to meet in London, got %v", meetups)
+	}
+	if meetups[0].Total != moneytest.GBP(180) {
+		t.Errorf("expected 180 for both, got %s", meetups[0].Total)
+	}
+}
+
+func TestSearch_MeetsAtHome(t *testing.T) {
+	p := &fakeFlights{
+		explores: map[string][]string{
+			"Berlin": {"LIS"},
+			"Madrid": {"LIS", "BER"},
+		},
+		fares: map[[2]string][]fare{
+			{"Berlin", "LIS"}: {{100, 18 * time.Hour}},
+			{"Madrid", "LIS"}: {{60, 19 * time.Hour}},
+			{"Madrid", "BER"}: {{50, 12 * time.Hour}},
+		},
+		adults: make(map[string]int),
+	}
+
+	res, err := Search(context.Background(), p.provider(), Request{
+		Origins:       []Origin{{Origin: "Berlin", Party: provider.Party{Adults: 1}}, {Origin: "Madrid", Party: provider.Party{Adults: 1}}},
+		DepartureDate: friday,
+		Currency:      currency.GBP,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meetups := res.Meetups
+
+	if len(meetups) != 2 {
+		t.Fatalf("expected Lisbon and Berlin, got %d meetups", len(meetups))
+	}
+	m := meetups[0]
+	if m.Destination != "BER" || m.Total != moneytest.GBP(50) {
+		t.Fatalf("expected meeting in Berlin for 50 to be cheapest, got %s for %s", m.Destination, m.Total)
+	}
+	if !m.Itineries[0].Home || m.Itineries[1].Home {
+		t.Error("expected only Berlin to be at home")
+	}
+	if m.Spread != 0 {
+		t.Errorf("expected no spread with one flight, got %v", m.Spread)
+	}
+	if !m.Itineries[0].Itinery.Price.IsZero() {
+		t.Errorf("expected no ticket for Berlin, got %s", m.Itineries[0].Itinery.Price)
+	}
+}
+
+func TestSearch_NeedsAnAdultPerOrigin(t *testing.T) {
+	req := request(0)
+	req.Origins[1].Adults = 0
+
+	if _, err := Search(context.Background(), newFakeFlights().provider(), req); err == nil {
+		t.Error("expected an error for an origin without adults")
+	}
+}
+
+func TestSearch_SkipsFailedDestination(t *testing.T) {
+	p := newFakeFlights()
+	p.failing = "AMS"
+
+	res, err := Search(context.Background(), p.provider(), request(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Meetups) != 1 || res.Meetups[0].Destination != "LIS" {
+		t.Fatalf("expected LIS to be found without AMS, got %v", res.Meetups)
+	}
+	if len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0], "AMS") {
+		t.Errorf("expected a warning that AMS was left out, got %v", res.Warnings)
+	}
+}
+
+func TestSearch_NegativeMaxCandidates(t *testing.T) {
+	req := request(0)
+	req.MaxCandidates = -1
+
+	res, err := Search(context.Background(), newFakeFlights().provider(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Meetups) != 2 {
+		t.Errorf("expected the default number of candidates, got %d meetups", len(res.Meetups))
+	}
+}
@@ -0,0 +1,75 @@
+// Package csvdata loads the csv datasets packages embed, which callers can
+// replace at runtime with their own.
+package csvdata
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Dataset is data parsed from a csv file, safe to read while it's replaced.
+type Dataset[T any] struct {
+	mu    sync.RWMutex
+	data  T
+	parse func(io.Reader) (T, error)
+}
+
+// Embed parses the csv a package embeds, panicking when it's invalid as
+// that's a bug in the package.
+func Embed[T any](pkg, csv string, parse func(io.Reader) (T, error)) *Dataset[T] {
+	data, err := parse(strings.NewReader(csv))
+	if err != nil {
+		panic(fmt.Sprintf("%s: invalid embedded dataset: %v", pkg, err))
+	}
+	return &Dataset[T]{data: data, parse: parse}
+}
+
+// Load replaces the data with what's parsed from r, keeping what's there when
+// r is invalid.
+func (d *Dataset[T]) Load(r io.Reader) error {
+	data, err := d.parse(r)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.data = data
+	d.mu.Unlock()
+
+	return nil
+}
+
+// Get returns the data, which is shared so mustn't be modified.
+func (d *Dataset[T]) Get() T {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.data
+}
+
+// Rows calls fn with every record after the header and its line number, for
+// errors. Every record must have n fields.
+func Rows(r io.Reader, n int, fn func(line int, rec []string) error) error {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return err
+	}
+
+	for i, rec := range records {
+		// skip the header
+		if i == 0 {
+			continue
+		}
+		if len(rec) != n {
+			return fmt.Errorf("line %d: expected %d fields, got %d", i+1, n, len(rec))
+		}
+		if err := fn(i+1, rec); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package csvdata
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func parseNames(r io.Reader) ([]string, error) {
+	names := make([]string, 0)
+	err := Rows(r, 2, func(line int, rec []string) error {
+		if rec[1] == "" {
+			return fmt.Errorf("line %d: missing name", line)
+		}
+		names = append(names, rec[1])
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func TestRows(t *testing.T) {
+	names, err := parseNames(strings.NewReader("code,name\nLHR,Heathrow\nLGW,Gatwick\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "Heathrow" {
+		t.Errorf("expected both rows after the header, got %v", names)
+	}
+
+	_, err = parseNames(strings.NewReader("code,name\nLHR,Heathrow\nLGW,\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("expected an error for line 3, got %v", err)
+	}
+
+	_, err = parseNames(strings.NewReader("code,name,city\nLHR,Heathrow,London\n"))
+	if err == nil || !strings.Contains(err.Error(), "expected 2 fields") {
+		t.Errorf("expected an error for the extra field, got %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	d := Embed("airport", "code,name\nLHR,Heathrow\n", parseNames)
+
+	if err := d.Load(strings.NewReader("code,name\nLGW,\n")); err == nil {
+		t.Fatal("expected an error for an invalid dataset")
+	}
+	if got := d.Get(); len(got) != 1 || got[0] != "Heathrow" {
+		t.Errorf("expected an invalid dataset to leave the data as it was, got %v", got)
+	}
+
+	if err := d.Load(strings.NewReader("code,name\nLGW,Gatwick\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Get(); len(got) != 1 || got[0] != "Gatwick" {
+		t.Errorf("expected the loaded data, got %v", got)
+	}
+}
+
+func TestEmbed_Invalid(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "airport: invalid embedded dataset") {
+			t.Errorf("expected a panic naming the package, got %v", r)
+		}
+	}()
+	Embed("airport", "code,name\nLHR\n", parseNames)
+}
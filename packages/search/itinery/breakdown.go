@@ -0,0 +1,142 @@
+package itinery
+
+import (
+	"github.com/tobyrushton/flyvia/packages/search/money"
+)
+
+type PassengerType string
+
+const (
+	Adult        PassengerType = "adult"
+	Youth        PassengerType = "youth"
+	Senior       PassengerType = "senior"
+	Child        PassengerType = "child"
+	InfantInSeat PassengerType = "infant-in-seat"
+	InfantOnLap  PassengerType = "infant-on-lap"
+)
+
+// the order fares are listed in.
+var passengerTypes = []PassengerType{Adult, Youth, Senior, Child, InfantInSeat, InfantOnLap}
+
+// lap infants don't take a seat and usually pay around a tenth of the adult
+// fare, everyone else is assumed to pay the same.
+var splitWeights = map[PassengerType]float64{
+	InfantOnLap: 0.1,
+}
+
+func weight(t PassengerType) float64 {
+	if w, ok := splitWeights[t]; ok {
+		return w
+	}
+	return 1
+}
+
+// Fare is what every passenger of one type on a ticket pays together.
+type Fare struct {
+	Type  PassengerType
+	Count int
+	// zero when the provider doesn't separate taxes from the base fare.
+	Base  money.Money
+	Taxes money.Money
+	Total money.Money
+}
+
+// Each is what a single passenger pays, rounded to the nearest minor unit.
+func (f Fare) Each() money.Money {
+	if f.Count == 0 {
+		return money.Zero(f.Total.Currency)
+	}
+	return f.Total.Mul(1 / float64(f.Count))
+}
+
+type Breakdown struct {
+	Fares []Fare
+	// the provider only gave a total, so it was split between passengers.
+	Estimated bool
+}
+
+// Split estimates a breakdown from a ticket total by sharing it between
+// every passenger, evenly apart from lap infants who pay a small share.
+func Split(total money.Money, passengers map[PassengerType]int) Breakdown {
+	weights := 0.0
+	for t, count := range passengers {
+		weights += weight(t) * float64(count)
+	}
+
+	b := Breakdown{Estimated: true}
+	remaining := total
+	for _, t := range passengerTypes {
+		count := passengers[t]
+		if count == 0 {
+			continue
+		}
+
+		share := total.Mul(weight(t) * float64(count) / weights)
+		// everything is in total's currency so this can't fail.
+		remaining, _ = remaining.Sub(share)
+
+		b.Fares = append(b.Fares, Fare{Type: t, Count: count, Total: share})
+	}
+
+	// rounding leftovers go to the first fare so the fares add up to total.
+	if len(b.Fares) > 0 {
+		b.Fares[0].Total, _ = b.Fares[0].Total.Add(remaining)
+	}
+
+	return b
+}
+
+// Fare returns the fare for a passenger type.
+func (b Breakdown) Fare(t PassengerType) (Fare, bool) {
+	for _, f := range b.Fares {
+		if f.Type == t {
+			return f, true
+		}
+	}
+	return Fare{}, false
+}
+
+func (b Breakdown) Total() (money.Money, error) {
+	total := money.Money{}
+	for _, f := range b.Fares {
+		var err error
+		if total, err = total.Add(f.Total); err != nil {
+			return money.Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// Merge adds the fares of o to b by passenger type, such as when totalling
+// the tickets of a split-ticket trip.
+func (b Breakdown) Merge(o Breakdown) (Breakdown, error) {
+	merged := Breakdown{Estimated: b.Estimated || o.Estimated}
+	merged.Fares = append(merged.Fares, b.Fares...)
+
+	for _, f := range o.Fares {
+		i := 0
+		for i < len(merged.Fares) && merged.Fares[i].Type != f.Type {
+			i++
+		}
+		if i == len(merged.Fares) {
+			merged.Fares = append(merged.Fares, f)
+			continue
+		}
+
+		m := merged.Fares[i]
+		var err error
+		if m.Base, err = m.Base.Add(f.Base); err != nil {
+			return Breakdown{}, err
+		}
+		if m.Taxes, err = m.Taxes.Add(f.Taxes); err != nil {
+			return Breakdown{}, err
+		}
+		if m.Total, err = m.Total.Add(f.Total); err != nil {
+			return Breakdown{}, err
+		}
+		m.Count = max(m.Count, f.Count)
+		merged.Fares[i] = m
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,66 @@
+package itinery
+
+import (
+	"testing"
+
+	"github.com/tobyrushton/flyvia/packages/search/money/moneytest"
+)
+
+func TestSplit(t *testing.T) {
+	b := Split(moneytest.GBP(100), map[PassengerType]int{Adult: 2, Child: 1})
+
+	if !b.Estimated {
+		t.Error("expected an even split to be marked as estimated")
+	}
+	if len(b.Fares) != 2 || b.Fares[0].Type != Adult {
+		t.Fatalf("expected adult then child fares, got %+v", b.Fares)
+	}
+
+	adult, _ := b.Fare(Adult)
+	if adult.Total != moneytest.GBP(66.67) || adult.Each() != moneytest.GBP(33.34) {
+		t.Errorf("expected adults to pay 66.67 together, got %s", adult.Total)
+	}
+
+	total, err := b.Total()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != moneytest.GBP(100) {
+		t.Errorf("expected fares to add back up to 100, got %s", total)
+	}
+}
+
+func TestSplit_LapInfant(t *testing.T) {
+	b := Split(moneytest.GBP(210), map[PassengerType]int{Adult: 2, InfantOnLap: 1})
+
+	infant, ok := b.Fare(InfantOnLap)
+	if !ok || infant.Total != moneytest.GBP(10) {
+		t.Errorf("expected a lap infant to pay a tenth of an adult, got %s", infant.Total)
+	}
+	if adult, _ := b.Fare(Adult); adult.Total != moneytest.GBP(200) {
+		t.Errorf("expected adults to pay 200, got %s", adult.Total)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	first := Split(moneytest.GBP(100), map[PassengerType]int{Adult: 1, Child: 1})
+	second := Breakdown{Fares: []Fare{
+		{Type: Adult, Count: 1, Base: moneytest.GBP(60), Taxes: moneytest.GBP(20), Total: moneytest.GBP(80)},
+	}}
+
+	merged, err := first.Merge(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adult, _ := merged.Fare(Adult)
+	if adult.Total != moneytest.GBP(130) || adult.Count != 1 {
+		t.Errorf("expected one adult paying 130 over both tickets, got %d paying %s", adult.Count, adult.Total)
+	}
+	if !merged.Estimated {
+		t.Error("expected merging an estimate to stay estimated")
+	}
+	if first.Fares[0].Total != moneytest.GBP(50) {
+		t.Error("expected merge to leave the original breakdown alone")
+	}
+}
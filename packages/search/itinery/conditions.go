@@ -0,0 +1,30 @@
+package itinery
+
+import "github.com/tobyrushton/flyvia/packages/search/money"
+
+// Conditions are a ticket's fare rules.
+type Conditions struct {
+	// the airline's name for the fare, such as "Economy Light".
+	Brand      string
+	Refundable bool
+	Changeable bool
+	// on top of any difference in fare, zero when changes are free or not
+	// allowed.
+	ChangeFee money.Money
+
+	// included for each passenger, bags over these are charged by
+	// baggage.Apply.
+	SeatSelection bool
+	CarryOn       bool
+	CheckedBags   int
+
+	// the provider gave no fare rules, so the inclusions are what the airline
+	// typically gives and the ticket is taken to be neither refundable nor
+	// changeable.
+	Estimated bool
+}
+
+// Flexible tickets can be changed or refunded.
+func (c Conditions) Flexible() bool {
+	return c.Refundable || c.Changeable
+}
@@ -0,0 +1,41 @@
+package itinery
+
+// Dedupe drops itineries flying the same flights as a cheaper one, as
+// codeshares sell the same flights under several flight numbers. The order of
+// those kept is unchanged.
+func Dedupe(itins []Itinery) []Itinery {
+	cheapest := make(map[string]int, len(itins))
+	kept := make([]Itinery, 0, len(itins))
+
+	for _, itin := range itins {
+		id := itin.Outbound.ID() + "|" + itin.Inbound.ID()
+		i, ok := cheapest[id]
+		if !ok {
+			cheapest[id] = len(kept)
+			kept = append(kept, itin)
+			continue
+		}
+		if c, err := itin.Price.Cmp(kept[i].Price); err == nil && c < 0 {
+			kept[i] = itin
+		}
+	}
+
+	return kept
+}
+
+// Cheapest returns the lowest priced of itins, the first when several tie,
+// false when there are none. Itineries priced in a different currency to the
+// first are skipped.
+func Cheapest(itins []Itinery) (Itinery, bool) {
+	if len(itins) == 0 {
+		return Itinery{}, false
+	}
+
+	cheapest := itins[0]
+	for _, itin := range itins[1:] {
+		if c, err := itin.Price.Cmp(cheapest.Price); err == nil && c < 0 {
+			cheapest = itin
+		}
+	}
+	return cheapest, true
+}
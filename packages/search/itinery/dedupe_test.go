@@ -0,0 +1,61 @@
+package itinery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"golang.org/x/text/currency"
+)
+
+func TestDedupe(t *testing.T) {
+	dep := time.Date(2030, 3, 1, 8, 0, 0, 0, time.UTC)
+	sold := func(code, operator string, price float64) Itinery {
+		f := leg.Flight{
+			DepartureAirport: "LHR",
+			ArrivalAirport:   "MAD",
+			DepartureTime:    dep,
+			FlightCode:       code,
+			AirlineCode:      code[:2],
+		}
+		if operator != f.AirlineCode {
+			f.OperatingAirlineCode = operator
+		}
+		return Itinery{
+			Outbound: leg.Leg{Flights: []leg.Flight{f}},
+			Price:    money.New(price, currency.GBP),
+		}
+	}
+	later := sold("IB3167", "IB", 90)
+	later.Outbound.Flights[0].DepartureTime = dep.Add(time.Hour)
+
+	kept := Dedupe([]Itinery{sold("IB3163", "IB", 120), sold("AA6132", "IB", 110), later, sold("BA7061", "IB", 130)})
+	if len(kept) != 2 {
+		t.Fatalf("expected the codeshares to be merged leaving 2, got %d", len(kept))
+	}
+	if kept[0].Outbound.Flights[0].FlightCode != "AA6132" {
+		t.Errorf("expected the cheapest codeshare to be kept, got %s", kept[0].Outbound.Flights[0].FlightCode)
+	}
+
+	// a different airline leaving at the same minute is another flight.
+	kept = Dedupe([]Itinery{sold("IB3163", "IB", 120), sold("UX1014", "UX", 80)})
+	if len(kept) != 2 {
+		t.Errorf("expected flights by different carriers to be kept apart, got %d", len(kept))
+	}
+}
+
+func TestCheapest(t *testing.T) {
+	if _, ok := Cheapest(nil); ok {
+		t.Error("expected nothing to be cheapest of no itineries")
+	}
+
+	itins := []Itinery{
+		{Price: money.New(120, currency.GBP), BookingURL: "a"},
+		{Price: money.New(90, currency.GBP), BookingURL: "b"},
+		{Price: money.New(90, currency.GBP), BookingURL: "c"},
+	}
+	if c, _ := Cheapest(itins); c.BookingURL != "b" {
+		t.Errorf("expected the first of the cheapest, got %s", c.BookingURL)
+	}
+}
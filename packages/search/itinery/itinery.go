@@ -1,18 +1,48 @@
 package itinery
 
 import (
+	"strings"
+	"time"
+
 	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money"
 )
 
 type Itinery struct {
-	Outbound   leg.Leg
-	Inbound    leg.Leg
-	Price      float64
+	Outbound leg.Leg
+	Inbound  leg.Leg
+	Price    money.Money
+	// how Price is shared between the passengers.
+	Breakdown  Breakdown
+	Conditions Conditions
 	BookingURL string
+	// when the provider gave the price.
+	ObservedAt time.Time
 }
 
-// simplified, wont contain flight details just the price and airports.
+// simplified, wont contain flight details just the price, airports and enough
+// information to run a follow-up search for the destination.
 type ExploreItinery struct {
+	Origin      string
 	Destination string
-	Price       float64
+	City        string
+	Country     string
+
+	DepartureDate time.Time
+	ReturnDate    time.Time
+
+	Stops int
+	// IATA codes, or the provider's name for airlines it can't be matched to.
+	Airlines     []string
+	MultiCarrier bool
+	// estimated from the great-circle distance when the provider doesn't expose it.
+	Duration time.Duration
+
+	Price money.Money
+}
+
+// Reaches reports whether ei goes to place, an airport or a city. Searches
+// are usually from a city name while explore results are airports.
+func (ei ExploreItinery) Reaches(place string) bool {
+	return ei.Destination == place || strings.EqualFold(ei.City, place)
 }
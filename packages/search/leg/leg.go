@@ -1,6 +1,33 @@
 package leg
 
-import "time"
+import (
+	"strings"
+	"time"
+)
+
+// Cabin is the class of travel, provider.Class is the same type.
+type Cabin int64
+
+const (
+	Economy Cabin = iota + 1
+	PremiumEconomy
+	Business
+	First
+)
+
+func (c Cabin) String() string {
+	switch c {
+	case Economy:
+		return "economy"
+	case PremiumEconomy:
+		return "premium economy"
+	case Business:
+		return "business"
+	case First:
+		return "first"
+	}
+	return "unknown"
+}
 
 type Leg struct {
 	Flights          []Flight
@@ -17,6 +44,62 @@ type Flight struct {
 	DepartureAirport string
 	ArrivalAirport   string
 	FlightCode       string
-	Plane            string
-	Airline          string
+	// as the provider names it, Aircraft is its family from aircraft.Lookup.
+	Plane    string
+	Aircraft string
+	// the airline selling the flight under FlightCode.
+	Airline     string
+	AirlineCode string
+	// the airline flying it when that's someone else, a codeshare, empty
+	// otherwise.
+	OperatingAirline     string
+	OperatingAirlineCode string
+	Cabin                Cabin
+}
+
+// Operator is the airline actually flying f.
+func (f Flight) Operator() (name, code string) {
+	if !f.Codeshare() {
+		return f.Airline, f.AirlineCode
+	}
+	return f.OperatingAirline, f.OperatingAirlineCode
+}
+
+func (f Flight) Codeshare() bool {
+	return f.OperatingAirline != "" || f.OperatingAirlineCode != ""
+}
+
+// ID identifies the physical flight whatever flight number it's sold under,
+// so codeshares of the same flight share one. It includes the operating
+// carrier, or the selling one when that isn't known, as different airlines
+// can fly the same route at the same minute.
+func (f Flight) ID() string {
+	_, code := f.Operator()
+	if code == "" {
+		code = f.AirlineCode
+	}
+	return code + " " + f.DepartureAirport + "-" + f.ArrivalAirport + " " + f.DepartureTime.UTC().Format("2006-01-02T15:04")
+}
+
+// String is the flight number and route, with who operates it for
+// codeshares, such as "AA6132 LHR-MAD operated by Iberia".
+func (f Flight) String() string {
+	s := f.FlightCode + " " + f.DepartureAirport + "-" + f.ArrivalAirport
+	if f.Codeshare() {
+		name, code := f.Operator()
+		if name == "" {
+			name = code
+		}
+		s += " operated by " + name
+	}
+	return s
+}
+
+// ID identifies the flights making up l, see Flight.ID.
+func (l Leg) ID() string {
+	ids := make([]string, len(l.Flights))
+	for i, f := range l.Flights {
+		ids[i] = f.ID()
+	}
+	return strings.Join(ids, ",")
 }
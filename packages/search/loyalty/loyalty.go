@@ -0,0 +1,156 @@
+package loyalty
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/airline"
+	"github.com/tobyrushton/flyvia/packages/search/airport"
+	"github.com/tobyrushton/flyvia/packages/search/internal/csvdata"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+)
+
+const kmPerMile = 1.609344
+
+// rows can name an alliance, prefixed with @, in place of an airline to cover
+// every airline in it. Rows for the airline win.
+const alliancePrefix = "@"
+
+// Rate is what a programme gives for each mile flown on an airline in a cabin.
+type Rate struct {
+	Points float64
+	Status float64
+	// short flights earn at least this many points.
+	MinPoints int
+}
+
+type key struct {
+	programme, airline string
+	cabin              leg.Cabin
+}
+
+//go:embed earning.csv
+var earningCSV string
+
+var rates = csvdata.Embed("loyalty", earningCSV, parse)
+
+var cabins = map[string]leg.Cabin{
+	"economy":         leg.Economy,
+	"premium-economy": leg.PremiumEconomy,
+	"business":        leg.Business,
+	"first":           leg.First,
+}
+
+// Load replaces the earning chart with the one read from r, in the same csv
+// format as the embedded earning.csv. The embedded chart is an approximation
+// of each programme's published earning, revenue-based programmes are
+// estimated by distance.
+func Load(r io.Reader) error {
+	return rates.Load(r)
+}
+
+func parse(r io.Reader) (map[key]Rate, error) {
+	parsed := make(map[key]Rate)
+	err := csvdata.Rows(r, 6, func(line int, rec []string) error {
+		cabin, ok := cabins[rec[2]]
+		if !ok {
+			return fmt.Errorf("line %d: unknown cabin %q", line, rec[2])
+		}
+		points, err := strconv.ParseFloat(rec[3], 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid points per mile: %w", line, err)
+		}
+		status, err := strconv.ParseFloat(rec[4], 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid status per mile: %w", line, err)
+		}
+		minPoints, err := strconv.Atoi(rec[5])
+		if err != nil {
+			return fmt.Errorf("line %d: invalid minimum points: %w", line, err)
+		}
+
+		parsed[key{strings.ToUpper(rec[0]), strings.ToLower(rec[1]), cabin}] = Rate{
+			Points:    points,
+			Status:    status,
+			MinPoints: minPoints,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return parsed, nil
+}
+
+// Lookup returns what programme gives for flying an airline, by IATA code, in
+// a cabin, false when it doesn't credit the airline.
+func Lookup(programme, code string, cabin leg.Cabin) (Rate, bool) {
+	programme = strings.ToUpper(programme)
+
+	chart := rates.Get()
+	if r, ok := chart[key{programme, strings.ToLower(code), cabin}]; ok {
+		return r, true
+	}
+	if a, ok := airline.Lookup(code); ok && a.Alliance != "" {
+		r, ok := chart[key{programme, alliancePrefix + string(a.Alliance), cabin}]
+		return r, ok
+	}
+	return Rate{}, false
+}
+
+// Flight returns the points and status credits f earns in programme, credited
+// by the airline operating it. False when the programme doesn't credit it or
+// the distance isn't known.
+func Flight(f leg.Flight, programme string) (points, status int, ok bool) {
+	a, ok := airline.Of(f)
+	if !ok {
+		return 0, 0, false
+	}
+	cabin := f.Cabin
+	if cabin == 0 {
+		cabin = leg.Economy
+	}
+	rate, ok := Lookup(programme, a.IATA, cabin)
+	if !ok {
+		return 0, 0, false
+	}
+	km, ok := airport.Distance(f.DepartureAirport, f.ArrivalAirport)
+	if !ok {
+		return 0, 0, false
+	}
+
+	miles := km / kmPerMile
+	points = max(rate.MinPoints, int(math.Round(miles*rate.Points)))
+	return points, int(math.Round(miles * rate.Status)), true
+}
+
+// Earn returns r with the points and status credits its flights earn, each
+// flight credited to whichever of the member's programmes gives the most
+// points for it.
+func Earn(r search.Result, programmes ...string) search.Result {
+	r.Points = make(map[string]int)
+	r.StatusCredits = make(map[string]int)
+
+	for _, f := range r.Flights() {
+		best, bestPoints, bestStatus := "", 0, 0
+		for _, p := range programmes {
+			points, status, ok := Flight(f, p)
+			if ok && points > bestPoints {
+				best, bestPoints, bestStatus = strings.ToUpper(p), points, status
+			}
+		}
+		if best == "" {
+			continue
+		}
+		r.Points[best] += bestPoints
+		r.StatusCredits[best] += bestStatus
+	}
+
+	return r
+}
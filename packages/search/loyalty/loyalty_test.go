@@ -0,0 +1,84 @@
+package loyalty
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/airport"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+)
+
+func miles(from, to string) float64 {
+	km, _ := airport.Distance(from, to)
+	return km / kmPerMile
+}
+
+func TestFlight(t *testing.T) {
+	ba := leg.Flight{DepartureAirport: "LHR", ArrivalAirport: "JFK", AirlineCode: "BA", Cabin: leg.Business}
+
+	points, status, ok := Flight(ba, "baec")
+	if want := int(math.Round(miles("LHR", "JFK") * 2.5)); !ok || points != want {
+		t.Errorf("expected %d points for BA business, got %d", want, points)
+	}
+	if status == 0 {
+		t.Error("expected status credits for BA business")
+	}
+
+	// partners in the alliance earn at the alliance rate.
+	qr := leg.Flight{DepartureAirport: "LHR", ArrivalAirport: "DOH", AirlineCode: "QR", Cabin: leg.Economy}
+	if points, _, _ := Flight(qr, "BAEC"); points != int(math.Round(miles("LHR", "DOH")*0.5)) {
+		t.Errorf("expected QR to earn half a point a mile, got %d", points)
+	}
+
+	// codeshares earn by who operates them.
+	codeshare := leg.Flight{
+		DepartureAirport: "LHR", ArrivalAirport: "JFK", AirlineCode: "AA",
+		OperatingAirline: "British Airways", OperatingAirlineCode: "BA", Cabin: leg.Business,
+	}
+	if points, _, _ := Flight(codeshare, "BAEC"); points != int(math.Round(miles("LHR", "JFK")*2.5)) {
+		t.Errorf("expected the codeshare to earn as BA, got %d", points)
+	}
+
+	if _, _, ok := Flight(leg.Flight{DepartureAirport: "LHR", ArrivalAirport: "JFK", AirlineCode: "LH"}, "BAEC"); ok {
+		t.Error("expected BAEC not to credit Lufthansa")
+	}
+
+	short := leg.Flight{DepartureAirport: "LHR", ArrivalAirport: "MAN", AirlineCode: "BA", Cabin: leg.Economy}
+	if points, _, _ := Flight(short, "BAEC"); points != 500 {
+		t.Errorf("expected the minimum of 500 points, got %d", points)
+	}
+}
+
+func TestEarn(t *testing.T) {
+	r := search.Result{Itineries: []itinery.Itinery{
+		{Outbound: leg.Leg{Flights: []leg.Flight{{DepartureAirport: "LHR", ArrivalAirport: "FRA", AirlineCode: "LH", Cabin: leg.Economy}}}},
+		{Outbound: leg.Leg{Flights: []leg.Flight{{DepartureAirport: "FRA", ArrivalAirport: "JFK", AirlineCode: "BA", Cabin: leg.Economy}}}},
+		{Outbound: leg.Leg{Flights: []leg.Flight{{DepartureAirport: "JFK", ArrivalAirport: "LHR", AirlineCode: "EK", Cabin: leg.Economy}}}},
+	}}
+
+	r = Earn(r, "MM", "BAEC", "AA")
+
+	if r.Points["MM"] != 500 {
+		t.Errorf("expected the Lufthansa flight credited to MM at its minimum, got %d", r.Points["MM"])
+	}
+	if want := int(math.Round(miles("FRA", "JFK"))); r.Points["BAEC"] != want {
+		t.Errorf("expected BA credited to BAEC over AA for %d points, got %+v", want, r.Points)
+	}
+	if _, ok := r.Points["AA"]; ok {
+		t.Errorf("expected nothing credited to AA, got %+v", r.Points)
+	}
+	if r.TotalPoints() != r.Points["MM"]+r.Points["BAEC"] {
+		t.Errorf("expected the total across programmes, got %d", r.TotalPoints())
+	}
+}
+
+func TestLoad(t *testing.T) {
+	defer Load(strings.NewReader(earningCSV))
+
+	if err := Load(strings.NewReader("programme,airline,cabin,points_per_mile,status_per_mile,min_points\nX,BA,coach,1,0,0\n")); err == nil {
+		t.Error("expected an error for an unknown cabin")
+	}
+}
@@ -0,0 +1,200 @@
+package money
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// Money is an amount in the minor units of its currency, such as pence for
+// GBP, so sums across tickets don't pick up float rounding errors.
+//
+// A zero Money with no currency adds to and compares with anything, so the
+// zero value can be used to start a sum.
+type Money struct {
+	Amount   int64
+	Currency currency.Unit
+}
+
+// New rounds amount, given in major units, to the minor units of cur.
+func New(amount float64, cur currency.Unit) Money {
+	return Money{
+		Amount:   int64(math.Round(amount * math.Pow10(scale(cur)))),
+		Currency: cur,
+	}
+}
+
+func Zero(cur currency.Unit) Money {
+	return Money{Currency: cur}
+}
+
+func scale(cur currency.Unit) int {
+	s, _ := currency.Standard.Rounding(cur)
+	return s
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) unset() bool {
+	return m.Amount == 0 && m.Currency == currency.Unit{}
+}
+
+// Float64 returns the amount in major units, for display and estimates only.
+func (m Money) Float64() float64 {
+	return float64(m.Amount) / math.Pow10(scale(m.Currency))
+}
+
+func (m Money) check(o Money) (currency.Unit, error) {
+	switch {
+	case m.unset():
+		return o.Currency, nil
+	case o.unset():
+		return m.Currency, nil
+	case m.Currency != o.Currency:
+		return currency.Unit{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	return m.Currency, nil
+}
+
+func (m Money) Add(o Money) (Money, error) {
+	cur, err := m.check(o)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: cur}, nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	cur, err := m.check(o)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount - o.Amount, Currency: cur}, nil
+}
+
+// Mul scales the amount, rounding to the nearest minor unit.
+func (m Money) Mul(f float64) Money {
+	return Money{
+		Amount:   int64(math.Round(float64(m.Amount) * f)),
+		Currency: m.Currency,
+	}
+}
+
+// Allocate splits m into n shares that add back up to m, the first shares
+// taking a minor unit more when it doesn't divide evenly.
+func (m Money) Allocate(n int) []Money {
+	if n <= 0 {
+		return nil
+	}
+
+	shares := make([]Money, n)
+	each, rem := m.Amount/int64(n), m.Amount%int64(n)
+	for i := range shares {
+		shares[i] = Money{Amount: each, Currency: m.Currency}
+		if int64(i) < rem {
+			shares[i].Amount++
+		}
+	}
+	return shares
+}
+
+// Cmp returns -1, 0 or 1 as m is less than, equal to or greater than o.
+func (m Money) Cmp(o Money) (int, error) {
+	if _, err := m.check(o); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.Amount < o.Amount:
+		return -1, nil
+	case m.Amount > o.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Sort sorts s by the price of each element, keeping the order of equal
+// prices. Prices in different currencies can't be ordered, so s is left as it
+// was and ErrCurrencyMismatch returned.
+func Sort[S ~[]E, E any](s S, price func(E) Money) error {
+	// the zero value takes on the currency of the first price that has one.
+	first := Money{}
+	for _, e := range s {
+		p := price(e)
+		if _, err := first.check(p); err != nil {
+			return err
+		}
+		if first.unset() {
+			first = Zero(p.Currency)
+		}
+	}
+
+	slices.SortStableFunc(s, func(a, b E) int {
+		return cmp.Compare(price(a).Amount, price(b).Amount)
+	})
+	return nil
+}
+
+func Sum(ms ...Money) (Money, error) {
+	total := Money{}
+	for _, m := range ms {
+		var err error
+		if total, err = total.Add(m); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// Format formats the amount with its currency symbol for the given locale.
+func (m Money) Format(tag language.Tag) string {
+	return message.NewPrinter(tag).Sprint(currency.Symbol(m.Currency.Amount(m.Float64())))
+}
+
+func (m Money) String() string {
+	return m.Currency.String() + " " + strconv.FormatFloat(m.Float64(), 'f', scale(m.Currency), 64)
+}
+
+type jsonMoney struct {
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+// MarshalJSON writes the amount in major units as a decimal string alongside
+// the currency code.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonMoney{
+		Amount:   json.Number(strconv.FormatFloat(m.Float64(), 'f', scale(m.Currency), 64)),
+		Currency: m.Currency.String(),
+	})
+}
+
+func (m *Money) UnmarshalJSON(b []byte) error {
+	var jm jsonMoney
+	if err := json.Unmarshal(b, &jm); err != nil {
+		return err
+	}
+
+	cur, err := currency.ParseISO(jm.Currency)
+	if err != nil {
+		return err
+	}
+	amount, err := jm.Amount.Float64()
+	if err != nil {
+		return err
+	}
+
+	*m = New(amount, cur)
+	return nil
+}
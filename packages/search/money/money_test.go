@@ -0,0 +1,135 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+)
+
+func TestNew(t *testing.T) {
+	if m := New(12.345, currency.GBP); m.Amount != 1235 {
+		t.Errorf("expected 1235 pence, got %d", m.Amount)
+	}
+	if m := New(1500.4, currency.JPY); m.Amount != 1500 {
+		t.Errorf("expected 1500 yen as yen have no minor units, got %d", m.Amount)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	// 0.1 + 0.2 is the classic float rounding error.
+	sum, err := New(0.1, currency.GBP).Add(New(0.2, currency.GBP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != New(0.3, currency.GBP) {
+		t.Errorf("expected GBP 0.30, got %s", sum)
+	}
+
+	if _, err := New(1, currency.GBP).Add(New(1, currency.EUR)); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("expected ErrCurrencyMismatch, got %v", err)
+	}
+
+	sum, err = Money{}.Add(New(5, currency.EUR))
+	if err != nil || sum.Currency != currency.EUR {
+		t.Errorf("expected the zero value to take on the currency, got %s %v", sum, err)
+	}
+}
+
+func TestSum(t *testing.T) {
+	sum, err := Sum(New(10, currency.USD), New(20.5, currency.USD), New(0.25, currency.USD))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Amount != 3075 {
+		t.Errorf("expected 3075 cents, got %d", sum.Amount)
+	}
+
+	if _, err := Sum(New(10, currency.USD), New(20, currency.GBP)); err == nil {
+		t.Error("expected an error summing mixed currencies")
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	shares := New(100, currency.GBP).Allocate(3)
+	if len(shares) != 3 {
+		t.Fatalf("expected 3 shares, got %d", len(shares))
+	}
+	if shares[0].Amount != 3334 || shares[2].Amount != 3333 {
+		t.Errorf("expected the remainder on the first share, got %v", shares)
+	}
+
+	sum, err := Sum(shares...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != New(100, currency.GBP) {
+		t.Errorf("expected shares to add back up to GBP 100.00, got %s", sum)
+	}
+}
+
+func TestCmp(t *testing.T) {
+	c, err := New(10, currency.GBP).Cmp(New(20, currency.GBP))
+	if err != nil || c != -1 {
+		t.Errorf("expected -1, got %d %v", c, err)
+	}
+
+	if _, err := New(10, currency.GBP).Cmp(New(10, currency.EUR)); err == nil {
+		t.Error("expected an error comparing mixed currencies")
+	}
+
+}
+
+func TestSort(t *testing.T) {
+	prices := []Money{New(12, currency.GBP), {}, New(10, currency.GBP), New(11, currency.GBP)}
+	if err := Sort(prices, func(m Money) Money { return m }); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []float64{0, 10, 11, 12} {
+		if prices[i].Float64() != want {
+			t.Errorf("expected %v at %d, got %s", want, i, prices[i])
+		}
+	}
+
+	mixed := []Money{New(12, currency.GBP), New(10, currency.EUR)}
+	if err := Sort(mixed, func(m Money) Money { return m }); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("expected ErrCurrencyMismatch sorting mixed currencies, got %v", err)
+	}
+	if mixed[0].Currency != currency.GBP {
+		t.Error("expected mixed currencies to be left in order")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	m := New(1234.5, currency.EUR)
+
+	if got := m.Format(language.English); got != "€ 1,234.50" {
+		t.Errorf("unexpected english format %q", got)
+	}
+	if got := m.Format(language.German); got != "€ 1.234,50" {
+		t.Errorf("unexpected german format %q", got)
+	}
+	if got := m.String(); got != "EUR 1234.50" {
+		t.Errorf("unexpected string %q", got)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	b, err := json.Marshal(New(99.99, currency.GBP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"amount":99.99,"currency":"GBP"}` {
+		t.Errorf("unexpected json %s", b)
+	}
+
+	var m Money
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m != New(99.99, currency.GBP) {
+		t.Errorf("expected GBP 99.99, got %s", m)
+	}
+}
@@ -0,0 +1,12 @@
+// Package moneytest has helpers for tests that deal in money.
+package moneytest
+
+import (
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"golang.org/x/text/currency"
+)
+
+// GBP is amount in pounds.
+func GBP(amount float64) money.Money {
+	return money.New(amount, currency.GBP)
+}
@@ -0,0 +1,62 @@
+package ontime
+
+import (
+	"time"
+)
+
+// delays are counted in buckets this wide, with the last bucket holding
+// everything later.
+const (
+	bucketWidth = 15 * time.Minute
+	buckets     = 25
+)
+
+// Distribution is how late a set of flights arrived.
+type Distribution struct {
+	Flights int `json:"flights"`
+	// arrived early or on time.
+	OnTime int `json:"on_time"`
+	// cancelled or diverted, which never arrive as booked.
+	Disrupted int `json:"disrupted"`
+	// counts of late flights by how late, in bucketWidth steps.
+	Delays [buckets]int `json:"delays"`
+}
+
+func (d *Distribution) add(delay time.Duration, disrupted bool) {
+	d.Flights++
+	switch {
+	case disrupted:
+		d.Disrupted++
+	case delay <= 0:
+		d.OnTime++
+	default:
+		i := int(delay / bucketWidth)
+		d.Delays[min(i, buckets-1)]++
+	}
+}
+
+// ProbLate is the share of flights arriving more than the duration late
+// behind schedule, including those that were cancelled or diverted.
+func (d Distribution) ProbLate(late time.Duration) float64 {
+	if d.Flights == 0 {
+		return 0
+	}
+
+	n := float64(d.Disrupted)
+	for i, count := range d.Delays {
+		lower := time.Duration(i) * bucketWidth
+		upper := lower + bucketWidth
+		switch {
+		case lower >= late:
+			n += float64(count)
+		case i == buckets-1:
+			// no upper bound to interpolate to, count it all.
+			n += float64(count)
+		case upper > late:
+			// assume delays are spread evenly through the bucket.
+			n += float64(count) * float64(upper-late) / float64(bucketWidth)
+		}
+	}
+
+	return n / float64(d.Flights)
+}
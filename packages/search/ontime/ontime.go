@@ -0,0 +1,292 @@
+package ontime
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/risk"
+)
+
+// distributions with fewer flights than this are too noisy to use, less
+// specific ones are tried instead.
+const minFlights = 20
+
+// the columns an import needs, by their names in BTS on-time performance
+// downloads. Headers are matched ignoring case and underscores so both the
+// current and older download formats work.
+var columns = map[string][]string{
+	"carrier":   {"opuniquecarrier", "opcarrier", "reportingairline", "uniquecarrier"},
+	"flight":    {"opcarrierflnum", "flightnumberreportingairline", "flnum"},
+	"origin":    {"origin"},
+	"dest":      {"dest"},
+	"deptime":   {"crsdeptime"},
+	"delay":     {"arrdelay"},
+	"cancelled": {"cancelled"},
+	"diverted":  {"diverted"},
+}
+
+// Store holds delay distributions built from imported on-time data, queryable
+// by flight, route, carrier and hour of departure.
+type Store struct {
+	mu sync.RWMutex
+
+	Flights  map[string]*Distribution `json:"flights"`
+	Routes   map[string]*Distribution `json:"routes"`
+	Carriers map[string]*Distribution `json:"carriers"`
+	// by carrier and scheduled departure hour, such as "AA 17".
+	CarrierHours map[string]*Distribution `json:"carrier_hours"`
+	// by scheduled departure hour across every carrier.
+	Hours map[int]*Distribution `json:"hours"`
+}
+
+func NewStore() *Store {
+	return &Store{
+		Flights:      make(map[string]*Distribution),
+		Routes:       make(map[string]*Distribution),
+		Carriers:     make(map[string]*Distribution),
+		CarrierHours: make(map[string]*Distribution),
+		Hours:        make(map[int]*Distribution),
+	}
+}
+
+// Load reads a store written by Save.
+func Load(r io.Reader) (*Store, error) {
+	s := NewStore()
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return nil, fmt.Errorf("decode store: %w", err)
+	}
+	return s, nil
+}
+
+func (s *Store) Save(w io.Writer) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return json.NewEncoder(w).Encode(s)
+}
+
+// Import adds every flight in a BTS on-time performance csv to the store,
+// returning how many were added. Rows missing a delay that weren't cancelled
+// or diverted are skipped.
+func (s *Store) Import(r io.Reader) (int, error) {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	// BTS files end every row with a trailing comma.
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return 0, fmt.Errorf("read header: %w", err)
+	}
+	idx, err := index(header)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+
+		field := func(col string) string {
+			if i := idx[col]; i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+
+		disrupted := isSet(field("cancelled")) || isSet(field("diverted"))
+
+		var delay time.Duration
+		if !disrupted {
+			raw := field("delay")
+			if raw == "" {
+				continue
+			}
+			mins, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return n, fmt.Errorf("line %d: invalid delay: %w", line, err)
+			}
+			delay = time.Duration(mins * float64(time.Minute))
+		}
+
+		hour, err := depHour(field("deptime"))
+		if err != nil {
+			return n, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		carrier := strings.ToUpper(field("carrier"))
+		s.add(s.Flights, flightKey(carrier, field("flight")), delay, disrupted)
+		s.add(s.Routes, routeKey(field("origin"), field("dest")), delay, disrupted)
+		s.add(s.Carriers, carrier, delay, disrupted)
+		s.add(s.CarrierHours, carrierHourKey(carrier, hour), delay, disrupted)
+
+		d, ok := s.Hours[hour]
+		if !ok {
+			d = &Distribution{}
+			s.Hours[hour] = d
+		}
+		d.add(delay, disrupted)
+
+		n++
+	}
+
+	return n, nil
+}
+
+func (s *Store) add(m map[string]*Distribution, key string, delay time.Duration, disrupted bool) {
+	d, ok := m[key]
+	if !ok {
+		d = &Distribution{}
+		m[key] = d
+	}
+	d.add(delay, disrupted)
+}
+
+// Flight returns the distribution for a flight number such as "AA100".
+func (s *Store) Flight(code string) (Distribution, bool) {
+	return s.get(s.Flights, normaliseFlight(code))
+}
+
+func (s *Store) Route(origin, dest string) (Distribution, bool) {
+	return s.get(s.Routes, routeKey(origin, dest))
+}
+
+func (s *Store) Carrier(code string) (Distribution, bool) {
+	return s.get(s.Carriers, strings.ToUpper(code))
+}
+
+func (s *Store) CarrierHour(code string, hour int) (Distribution, bool) {
+	return s.get(s.CarrierHours, carrierHourKey(strings.ToUpper(code), hour))
+}
+
+func (s *Store) Hour(hour int) (Distribution, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.Hours[hour]
+	if !ok {
+		return Distribution{}, false
+	}
+	return *d, true
+}
+
+func (s *Store) get(m map[string]*Distribution, key string) (Distribution, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := m[key]
+	if !ok {
+		return Distribution{}, false
+	}
+	return *d, true
+}
+
+// ProbLate uses the most specific distribution with enough flights behind it,
+// trying the flight, its route, its carrier at that hour, the carrier, then
+// the hour. Delays are recorded against who operates a flight, so carriers
+// are looked up by the operating airline, as in leg.Flight.ID, and codeshares
+// skip the flight as only their marketing number is known.
+func (s *Store) ProbLate(f leg.Flight, late time.Duration) (float64, bool) {
+	hour := f.DepartureTime.Hour()
+	_, carrier := f.Operator()
+	if carrier == "" {
+		carrier = f.AirlineCode
+	}
+
+	lookups := []func() (Distribution, bool){
+		func() (Distribution, bool) {
+			if f.Codeshare() {
+				return Distribution{}, false
+			}
+			return s.Flight(f.FlightCode)
+		},
+		func() (Distribution, bool) { return s.Route(f.DepartureAirport, f.ArrivalAirport) },
+		func() (Distribution, bool) { return s.CarrierHour(carrier, hour) },
+		func() (Distribution, bool) { return s.Carrier(carrier) },
+		func() (Distribution, bool) { return s.Hour(hour) },
+	}
+
+	for _, lookup := range lookups {
+		if d, ok := lookup(); ok && d.Flights >= minFlights {
+			return d.ProbLate(late), true
+		}
+	}
+	return 0, false
+}
+
+func index(header []string) (map[string]int, error) {
+	positions := make(map[string]int, len(header))
+	for i, h := range header {
+		positions[strings.ReplaceAll(strings.ToLower(strings.TrimSpace(h)), "_", "")] = i
+	}
+
+	idx := make(map[string]int, len(columns))
+	for col, names := range columns {
+		found := false
+		for _, name := range names {
+			if i, ok := positions[name]; ok {
+				idx[col], found = i, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("missing %s column, expected one of %s", col, strings.Join(names, ", "))
+		}
+	}
+	return idx, nil
+}
+
+// depHour reads the hour from an hhmm time, BTS writes midnight as 2400.
+func depHour(hhmm string) (int, error) {
+	t, err := strconv.Atoi(hhmm)
+	if err != nil {
+		return 0, fmt.Errorf("invalid departure time %q", hhmm)
+	}
+	return (t / 100) % 24, nil
+}
+
+func isSet(flag string) bool {
+	f, err := strconv.ParseFloat(flag, 64)
+	return err == nil && f != 0
+}
+
+func flightKey(carrier, number string) string {
+	return normaliseFlight(carrier + number)
+}
+
+// normaliseFlight drops spaces and leading zeros from the number, so "BA 0117"
+// and "BA117" match.
+func normaliseFlight(code string) string {
+	code = strings.ToUpper(strings.ReplaceAll(code, " ", ""))
+	if len(code) <= 2 {
+		return code
+	}
+	return code[:2] + strings.TrimLeft(code[2:], "0")
+}
+
+func routeKey(origin, dest string) string {
+	return strings.ToUpper(origin) + "-" + strings.ToUpper(dest)
+}
+
+func carrierHourKey(carrier string, hour int) string {
+	return carrier + " " + strconv.Itoa(hour)
+}
+
+var _ risk.DelaySource = (*Store)(nil)
@@ -0,0 +1,134 @@
+package ontime
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+)
+
+// csvRows builds a BTS style download with a trailing comma on every row, n
+// flights of AA100 JFK-LAX at 17:30 arriving delay minutes late.
+func csvRows(header bool, n int, delay string) string {
+	var b strings.Builder
+	if header {
+		b.WriteString(`"FL_DATE","OP_UNIQUE_CARRIER","OP_CARRIER_FL_NUM","ORIGIN","DEST","CRS_DEP_TIME","ARR_DELAY","CANCELLED","DIVERTED",` + "\n")
+	}
+	for range n {
+		fmt.Fprintf(&b, "2024-01-01,AA,100,JFK,LAX,1730,%s,0.00,0.00,\n", delay)
+	}
+	return b.String()
+}
+
+func TestImport(t *testing.T) {
+	data := csvRows(true, 10, "-5.00") +
+		csvRows(false, 6, "30.00") +
+		csvRows(false, 2, "") +
+		"2024-01-02,AA,100,JFK,LAX,1730,,1.00,0.00,\n" +
+		"2024-01-03,AA,0100,JFK,LAX,1730,,0.00,1.00,\n" +
+		"2024-01-03,DL,5,JFK,ATL,0600,10.00,0.00,0.00,\n"
+
+	s := NewStore()
+	n, err := s.Import(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 19 {
+		t.Errorf("expected 19 flights imported, skipping those without a delay, got %d", n)
+	}
+
+	d, ok := s.Flight("AA 100")
+	if !ok || d.Flights != 18 || d.Disrupted != 2 || d.OnTime != 10 {
+		t.Fatalf("expected 18 AA100 flights, 2 disrupted and 10 on time, got %+v", d)
+	}
+
+	// the 6 at 30 minutes late and the 2 disrupted.
+	if p := d.ProbLate(20 * time.Minute); math.Abs(p-8.0/18) > 1e-9 {
+		t.Errorf("expected 8/18 over 20 minutes late, got %v", p)
+	}
+	if p := d.ProbLate(45 * time.Minute); math.Abs(p-2.0/18) > 1e-9 {
+		t.Errorf("expected only disruptions over 45 minutes late, got %v", p)
+	}
+
+	if d, ok := s.CarrierHour("aa", 17); !ok || d.Flights != 18 {
+		t.Errorf("expected 18 AA flights at 17:00, got %+v", d)
+	}
+	if d, ok := s.Route("JFK", "ATL"); !ok || d.Flights != 1 {
+		t.Errorf("expected 1 JFK-ATL flight, got %+v", d)
+	}
+}
+
+func TestImport_MissingColumn(t *testing.T) {
+	_, err := NewStore().Import(strings.NewReader("FL_DATE,ORIGIN,DEST\n2024-01-01,JFK,LAX\n"))
+	if err == nil {
+		t.Error("expected an error for a file missing columns")
+	}
+}
+
+func TestProbLate_FallsBack(t *testing.T) {
+	s := NewStore()
+	if _, err := s.Import(strings.NewReader(csvRows(true, 30, "60.00"))); err != nil {
+		t.Fatal(err)
+	}
+
+	// an unknown flight on a known route uses the route.
+	p, ok := s.ProbLate(leg.Flight{
+		FlightCode:       "AA999",
+		AirlineCode:      "AA",
+		DepartureAirport: "JFK",
+		ArrivalAirport:   "LAX",
+		DepartureTime:    time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC),
+	}, 30*time.Minute)
+	if !ok || p != 1 {
+		t.Errorf("expected every flight on the route to be over 30 minutes late, got %v", p)
+	}
+
+	if _, ok := s.ProbLate(leg.Flight{FlightCode: "BA1", AirlineCode: "BA", DepartureAirport: "LHR", ArrivalAirport: "CDG"}, time.Hour); ok {
+		t.Error("expected nothing known about a BA flight at midnight")
+	}
+}
+
+func TestProbLate_Codeshare(t *testing.T) {
+	s := NewStore()
+	data := csvRows(true, 30, "60.00") + strings.Repeat("2024-01-01,BA,100,LHR,CDG,1730,-5.00,0.00,0.00,\n", 30)
+	if _, err := s.Import(strings.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	// AA100 sold on a BA flight is BA's to be late, not the AA100 to LAX.
+	p, ok := s.ProbLate(leg.Flight{
+		FlightCode:           "AA100",
+		AirlineCode:          "AA",
+		OperatingAirlineCode: "BA",
+		DepartureAirport:     "LHR",
+		ArrivalAirport:       "MAN",
+		DepartureTime:        time.Date(2030, 1, 1, 17, 30, 0, 0, time.UTC),
+	}, 30*time.Minute)
+	if !ok || p != 0 {
+		t.Errorf("expected the operating carrier's record of never being late, got %v", p)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	s := NewStore()
+	if _, err := s.Import(strings.NewReader(csvRows(true, 3, "20.00"))); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := s.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := loaded.Hour(17); !ok || d.Flights != 3 {
+		t.Errorf("expected 3 flights at 17:00 after loading, got %+v", d)
+	}
+}
@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"golang.org/x/text/currency"
+)
+
+// DatesRequest prices a route for every departure day in a range.
+type DatesRequest struct {
+	Origin      string
+	Destination string
+
+	DepartureFrom time.Time
+	DepartureTo   time.Time
+
+	// trip lengths in days, both zero prices one-way trips.
+	MinTripLength int
+	MaxTripLength int
+
+	Party
+
+	Currency currency.Unit
+	Class    Class
+}
+
+func (r DatesRequest) Validate() error {
+	if r.Origin == "" || r.Destination == "" {
+		return fmt.Errorf("origin and destination are required")
+	}
+	if err := r.Party.Validate(); err != nil {
+		return err
+	}
+	if r.DepartureFrom.IsZero() || r.DepartureTo.Before(r.DepartureFrom) {
+		return fmt.Errorf("invalid departure range")
+	}
+	if r.MinTripLength < 0 || r.MaxTripLength < r.MinTripLength {
+		return fmt.Errorf("invalid trip length range %d-%d", r.MinTripLength, r.MaxTripLength)
+	}
+	if r.MinTripLength == 0 && r.MaxTripLength > 0 {
+		return fmt.Errorf("return trips must be at least 1 day long, got a range of 0-%d", r.MaxTripLength)
+	}
+	return nil
+}
+
+// Key identifies the request, requests with the same key give the same
+// prices.
+func (r DatesRequest) Key() string {
+	return fmt.Sprintf(
+		"%s|%s|%s|%s|%d|%d|%s|%s|%d",
+		r.Origin,
+		r.Destination,
+		r.DepartureFrom.Format(time.DateOnly),
+		r.DepartureTo.Format(time.DateOnly),
+		r.MinTripLength,
+		r.MaxTripLength,
+		r.Party.key(),
+		r.Currency,
+		r.Class,
+	)
+}
+
+func (r DatesRequest) OneWay() bool {
+	return r.MaxTripLength == 0
+}
+
+// DatePrice is the cheapest fare for a trip on a pair of dates.
+type DatePrice struct {
+	DepartureDate time.Time
+	// zero for one-way trips.
+	ReturnDate time.Time
+	Price      money.Money
+}
+
+// DatePricer is a provider that can price a whole range of dates in one call,
+// far cheaper than a search or explore per day. Dates with no fare are left
+// out.
+type DatePricer interface {
+	Dates(ctx context.Context, req DatesRequest) ([]DatePrice, error)
+}
+
+// covers reports whether p is a trip the request asked for.
+func (r DatesRequest) covers(p DatePrice) bool {
+	dep := p.DepartureDate
+	if Days(r.DepartureFrom, dep) < 0 || Days(dep, r.DepartureTo) < 0 {
+		return false
+	}
+	if r.OneWay() {
+		return true
+	}
+	length := Days(dep, p.ReturnDate)
+	return length >= r.MinTripLength && length <= r.MaxTripLength
+}
@@ -0,0 +1,204 @@
+package provider
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/airport"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"golang.org/x/text/currency"
+)
+
+const (
+	// longest departure window explored, a month of days.
+	maxExploreDays = 31
+	// most provider calls an explore can fan out to, one per origin and
+	// date pair.
+	maxExploreCalls = 200
+)
+
+type ExploreRequest struct {
+	Origins []string
+
+	// every departure day between DepartureFrom and DepartureTo is explored,
+	// a zero DepartureTo only explores DepartureFrom.
+	DepartureFrom time.Time
+	DepartureTo   time.Time
+
+	// trip lengths in days, both zero explores one-way trips. Return trips
+	// are at least a day long.
+	MinTripLength int
+	MaxTripLength int
+
+	// zero means no limit.
+	MaxPrice money.Money
+	// destinations must be in one of these regions or countries when set.
+	Regions   []airport.Region
+	Countries []string
+
+	Party
+
+	Currency currency.Unit
+	Class    Class
+}
+
+func (r ExploreRequest) Validate() error {
+	if len(r.Origins) == 0 {
+		return fmt.Errorf("at least one origin is required")
+	}
+	if err := r.Party.Validate(); err != nil {
+		return err
+	}
+	if r.DepartureFrom.IsZero() {
+		return fmt.Errorf("departure date is required")
+	}
+	if !r.DepartureTo.IsZero() && r.DepartureTo.Before(r.DepartureFrom) {
+		return fmt.Errorf("departure window ends before it starts")
+	}
+	if r.MinTripLength < 0 || r.MaxTripLength < r.MinTripLength {
+		return fmt.Errorf("invalid trip length range %d-%d", r.MinTripLength, r.MaxTripLength)
+	}
+	if r.MinTripLength == 0 && r.MaxTripLength > 0 {
+		return fmt.Errorf("return trips must be at least 1 day long, got a range of 0-%d", r.MaxTripLength)
+	}
+	if r.MaxPrice.Amount < 0 {
+		return fmt.Errorf("max price cannot be negative")
+	}
+	if !r.MaxPrice.IsZero() && r.MaxPrice.Currency != r.Currency {
+		return fmt.Errorf("%w: max price in %s", money.ErrCurrencyMismatch, r.MaxPrice.Currency)
+	}
+	if days := r.days(); days > maxExploreDays {
+		return fmt.Errorf("departure window of %d days is over the %d day limit", days, maxExploreDays)
+	}
+	if calls := r.calls(); calls > maxExploreCalls {
+		return fmt.Errorf("explore needs %d calls, over the limit of %d", calls, maxExploreCalls)
+	}
+	return nil
+}
+
+// days is the number of departure days explored.
+func (r ExploreRequest) days() int {
+	if r.DepartureTo.IsZero() {
+		return 1
+	}
+	n := 0
+	for d := r.DepartureFrom; !d.After(r.DepartureTo); d = d.AddDate(0, 0, 1) {
+		n++
+	}
+	return n
+}
+
+// calls is the number of provider calls the explore fans out to.
+func (r ExploreRequest) calls() int {
+	lengths := 1
+	if !r.OneWay() {
+		lengths = r.MaxTripLength - r.MinTripLength + 1
+	}
+	return len(r.Origins) * r.days() * lengths
+}
+
+// Key identifies the explore, requests with the same key give the same
+// results.
+func (r ExploreRequest) Key() string {
+	return fmt.Sprintf(
+		"%v|%s|%s|%d|%d|%v|%v|%v|%s|%s|%d",
+		r.Origins,
+		r.DepartureFrom.Format(time.DateOnly),
+		r.DepartureTo.Format(time.DateOnly),
+		r.MinTripLength,
+		r.MaxTripLength,
+		r.MaxPrice,
+		r.Regions,
+		r.Countries,
+		r.Party.key(),
+		r.Currency,
+		r.Class,
+	)
+}
+
+func (r ExploreRequest) OneWay() bool {
+	return r.MaxTripLength == 0
+}
+
+type trip struct {
+	departure time.Time
+	ret       time.Time
+}
+
+// trips expands the departure window and trip lengths into every date pair to explore.
+func (r ExploreRequest) trips() []trip {
+	last := r.DepartureTo
+	if last.IsZero() {
+		last = r.DepartureFrom
+	}
+
+	trips := make([]trip, 0)
+	for d := r.DepartureFrom; !d.After(last); d = d.AddDate(0, 0, 1) {
+		if r.OneWay() {
+			trips = append(trips, trip{departure: d})
+			continue
+		}
+		for l := r.MinTripLength; l <= r.MaxTripLength; l++ {
+			trips = append(trips, trip{departure: d, ret: d.AddDate(0, 0, l)})
+		}
+	}
+
+	return trips
+}
+
+// Matches reports whether an explore result satisfies the price, region and
+// country filters of the request.
+func (r ExploreRequest) Matches(ei itinery.ExploreItinery) bool {
+	if !r.MaxPrice.IsZero() {
+		if c, err := ei.Price.Cmp(r.MaxPrice); err != nil || c > 0 {
+			return false
+		}
+	}
+	if len(r.Regions) == 0 && len(r.Countries) == 0 {
+		return true
+	}
+
+	a, ok := airport.Lookup(ei.Destination)
+	if !ok {
+		return false
+	}
+
+	return slices.Contains(r.Regions, a.Region) || slices.Contains(r.Countries, a.Country)
+}
+
+// FollowUp builds a search request for a destination found through Explore,
+// keeping the passengers, class and currency of r.
+func (r ExploreRequest) FollowUp(ei itinery.ExploreItinery) Request {
+	return Request{
+		Origin:        ei.Origin,
+		Destination:   ei.Destination,
+		DepartureDate: ei.DepartureDate,
+		ReturnDate:    ei.ReturnDate,
+		Party:         r.Party,
+		Currency:      r.Currency,
+		Class:         r.Class,
+	}
+}
+
+// cheapest keeps the cheapest explore result for each origin and destination.
+func cheapest(eis []itinery.ExploreItinery) []itinery.ExploreItinery {
+	best := make(map[[2]string]int)
+	out := make([]itinery.ExploreItinery, 0, len(eis))
+
+	for _, ei := range eis {
+		key := [2]string{ei.Origin, ei.Destination}
+		i, ok := best[key]
+		if !ok {
+			best[key] = len(out)
+			out = append(out, ei)
+			continue
+		}
+		if c, err := ei.Price.Cmp(out[i].Price); err == nil && c < 0 {
+			out[i] = ei
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/airport"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"github.com/tobyrushton/flyvia/packages/search/money/moneytest"
+	"golang.org/x/text/currency"
+)
+
+func TestExploreRequestTrips(t *testing.T) {
+	from := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	req := ExploreRequest{
+		Origins:       []string{"London"},
+		DepartureFrom: from,
+		DepartureTo:   from.AddDate(0, 0, 2),
+		MinTripLength: 3,
+		MaxTripLength: 4,
+	}
+
+	trips := req.trips()
+	if len(trips) != 6 {
+		t.Fatalf("expected 6 trips (3 days x 2 lengths), got %d", len(trips))
+	}
+	if !trips[1].ret.Equal(from.AddDate(0, 0, 4)) {
+		t.Errorf("expected second trip to return after 4 days, got %v", trips[1].ret)
+	}
+
+	req.MinTripLength, req.MaxTripLength = 0, 0
+	trips = req.trips()
+	if len(trips) != 3 {
+		t.Fatalf("expected 3 one-way trips, got %d", len(trips))
+	}
+	if !trips[0].ret.IsZero() {
+		t.Error("expected one-way trips to have no return date")
+	}
+}
+
+func TestExploreRequestValidate(t *testing.T) {
+	from := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		req     ExploreRequest
+		wantErr bool
+	}{
+		{"valid", ExploreRequest{Origins: []string{"London"}, DepartureFrom: from, Party: Party{Adults: 1}}, false},
+		{"no passengers", ExploreRequest{Origins: []string{"London"}, DepartureFrom: from}, true},
+		{"lap infant for a youth", ExploreRequest{Origins: []string{"London"}, DepartureFrom: from, Party: Party{Youths: 1, InfantsOnLap: 1}}, true},
+		{"no origins", ExploreRequest{DepartureFrom: from}, true},
+		{"no departure", ExploreRequest{Origins: []string{"London"}}, true},
+		{"window backwards", ExploreRequest{Origins: []string{"London"}, DepartureFrom: from, DepartureTo: from.AddDate(0, 0, -1)}, true},
+		{"lengths backwards", ExploreRequest{Origins: []string{"London"}, DepartureFrom: from, MinTripLength: 5, MaxTripLength: 2}, true},
+		{"same day return", ExploreRequest{Origins: []string{"London"}, DepartureFrom: from, MaxTripLength: 3}, true},
+		{"negative price", ExploreRequest{Origins: []string{"London"}, DepartureFrom: from, MaxPrice: moneytest.GBP(-1)}, true},
+		{"price in another currency", ExploreRequest{Origins: []string{"London"}, DepartureFrom: from, Party: Party{Adults: 1}, MaxPrice: moneytest.GBP(300), Currency: currency.EUR}, true},
+		{"window too long", ExploreRequest{Origins: []string{"London"}, DepartureFrom: from, DepartureTo: from.AddDate(0, 2, 0), Party: Party{Adults: 1}}, true},
+		{"too many calls", ExploreRequest{Origins: []string{"London", "Paris"}, DepartureFrom: from, DepartureTo: from.AddDate(0, 0, 20), MinTripLength: 1, MaxTripLength: 14, Party: Party{Adults: 1}}, true},
+	}
+
+	for _, tt := range tests {
+		err := tt.req.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestExploreRequestMatches(t *testing.T) {
+	req := ExploreRequest{
+		MaxPrice:  money.New(300, currency.GBP),
+		Regions:   []airport.Region{airport.Asia},
+		Countries: []string{"ES"},
+	}
+
+	tests := []struct {
+		ei   itinery.ExploreItinery
+		want bool
+	}{
+		{itinery.ExploreItinery{Destination: "BKK", Price: moneytest.GBP(250)}, true},
+		{itinery.ExploreItinery{Destination: "MAD", Price: moneytest.GBP(100)}, true},
+		{itinery.ExploreItinery{Destination: "BKK", Price: moneytest.GBP(350)}, false},
+		{itinery.ExploreItinery{Destination: "JFK", Price: moneytest.GBP(100)}, false},
+		{itinery.ExploreItinery{Destination: "XXX", Price: moneytest.GBP(100)}, false},
+		{itinery.ExploreItinery{Destination: "BKK", Price: money.New(100, currency.EUR)}, false},
+	}
+
+	for _, tt := range tests {
+		if got := req.Matches(tt.ei); got != tt.want {
+			t.Errorf("%s at %s: expected %v, got %v", tt.ei.Destination, tt.ei.Price, tt.want, got)
+		}
+	}
+}
+
+func TestCheapest(t *testing.T) {
+	eis := cheapest([]itinery.ExploreItinery{
+		{Origin: "London", Destination: "MAD", Price: moneytest.GBP(120)},
+		{Origin: "London", Destination: "MAD", Price: moneytest.GBP(80)},
+		{Origin: "Paris", Destination: "MAD", Price: moneytest.GBP(90)},
+	})
+
+	if len(eis) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(eis))
+	}
+	if eis[0].Price != moneytest.GBP(80) {
+		t.Errorf("expected cheapest London-MAD to be 80, got %s", eis[0].Price)
+	}
+}
+
+func TestExploreRequestFollowUp(t *testing.T) {
+	req := ExploreRequest{
+		Origins: []string{"London"},
+		Party:   Party{Adults: 2, Children: 1, ChildAges: []int{4}, InfantsOnLap: 1, Seniors: 1},
+	}
+
+	follow := req.FollowUp(itinery.ExploreItinery{Origin: "LHR", Destination: "JFK"})
+	if follow.InfantsOnLap != 1 || follow.Seniors != 1 || len(follow.ChildAges) != 1 {
+		t.Errorf("expected the whole party to be kept, got %+v", follow.Party)
+	}
+}
@@ -2,17 +2,33 @@ package provider
 
 import (
 	"context"
-	"fmt"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/tobyrushton/flyvia/packages/search/aircraft"
+	"github.com/tobyrushton/flyvia/packages/search/airline"
+	"github.com/tobyrushton/flyvia/packages/search/airport"
+	"github.com/tobyrushton/flyvia/packages/search/baggage"
 	"github.com/tobyrushton/flyvia/packages/search/itinery"
 	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money"
 	"github.com/tobyrushton/gflights"
+	"github.com/tobyrushton/gflights/iata"
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
 )
 
+// the number of calls to google run at once when one search needs several.
+const defaultConcurrency = 4
+
 type GFlights struct {
 	s *gflights.Session
+
+	// number of calls to google run at once by Explore, which makes one per
+	// origin, departure day and trip length.
+	Concurrency int
 }
 
 func NewGFlights() (*GFlights, error) {
@@ -22,71 +38,227 @@ func NewGFlights() (*GFlights, error) {
 	}
 
 	return &GFlights{
-		s: s,
+		s:           s,
+		Concurrency: defaultConcurrency,
 	}, nil
 }
 
 func (g *GFlights) Explore(
 	ctx context.Context,
-	req Request,
-	origin string,
+	req ExploreRequest,
 ) ([]itinery.ExploreItinery, error) {
-	offers, err := g.s.GetExplore(ctx, gflights.ExploreArgs{
-		DepartureDate: req.DepartureDate,
-		ReturnDate:    req.ReturnDate,
-		SrcCities:     []string{origin},
-		Options: gflights.Options{
-			Travelers: gflights.Travelers{
-				Adults:   req.Adults,
-				Children: req.Children,
-			},
-			Class:    gflights.Class(req.Class),
-			Currency: req.Currency,
-			TripType: gflights.RoundTrip,
-			Stops:    gflights.AnyStops,
-		},
-	})
-
-	if err != nil {
+	if err := req.Validate(); err != nil {
 		return nil, err
 	}
 
+	tripType := gflights.RoundTrip
+	if req.OneWay() {
+		tripType = gflights.OneWay
+	}
+
 	ei := make([]itinery.ExploreItinery, 0)
+	wg := sync.WaitGroup{}
+	eiMu := sync.Mutex{}
+	var firstErr error
+	sem := make(chan struct{}, max(1, g.Concurrency))
+
+	// explore offers don't say which origin they are from, so each origin
+	// needs its own request.
+	for _, origin := range req.Origins {
+		for _, t := range req.trips() {
+			wg.Add(1)
+			go func(origin string, t trip) {
+				defer wg.Done()
+
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				srcCities, srcAirports := locations(origin)
+				offers, err := g.s.GetExplore(ctx, gflights.ExploreArgs{
+					DepartureDate: t.departure,
+					ReturnDate:    t.ret,
+					SrcCities:     srcCities,
+					SrcAirports:   srcAirports,
+					Options: gflights.Options{
+						Travelers: req.travelers(),
+						Class:     gflights.Class(req.Class),
+						Currency:  req.Currency,
+						TripType:  tripType,
+						Stops:     gflights.AnyStops,
+					},
+				})
+
+				eiMu.Lock()
+				defer eiMu.Unlock()
+
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+					return
+				}
+
+				for _, offer := range offers {
+					e := gflightsExploreOfferToExploreItinery(offer, origin, t, req.Currency)
+					if req.Matches(e) {
+						ei = append(ei, e)
+					}
+				}
+			}(origin, t)
+		}
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
 
-	for _, offer := range offers {
-		ei = append(ei, itinery.ExploreItinery{
-			Destination: offer.AirportCode,
-			Price:       float64(offer.Price),
+	return cheapest(ei), nil
+}
+
+// Dates prices every departure day in the range in a single call, with the
+// price graph for one-way trips or a single trip length and the price grid
+// for a range of lengths.
+func (g *GFlights) Dates(
+	ctx context.Context,
+	req DatesRequest,
+) ([]DatePrice, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	srcCities, srcAirports := locations(req.Origin)
+	dstCities, dstAirports := locations(req.Destination)
+	opts := gflights.Options{
+		Travelers: req.travelers(),
+		Class:     gflights.Class(req.Class),
+		Currency:  req.Currency,
+		TripType:  gflights.RoundTrip,
+		Stops:     gflights.AnyStops,
+	}
+
+	// gflights ranges have to span at least a day, extra days are dropped
+	// below.
+	from, to := req.DepartureFrom, req.DepartureTo
+	if Days(from, to) < 1 {
+		to = from.AddDate(0, 0, 1)
+	}
+
+	var offers []gflights.SimpleOffer
+	var err error
+	if req.OneWay() || req.MinTripLength == req.MaxTripLength {
+		length := req.MinTripLength
+		if req.OneWay() {
+			// gflights wants a length even for one-way trips.
+			opts.TripType, length = gflights.OneWay, 1
+		}
+		offers, err = g.s.GetPriceGraph(ctx, gflights.PriceGraphArgs{
+			RangeStartDate: from,
+			RangeEndDate:   to,
+			TripLength:     length,
+			SrcCities:      srcCities,
+			SrcAirports:    srcAirports,
+			DstCities:      dstCities,
+			DstAirports:    dstAirports,
+			Options:        opts,
+		})
+	} else {
+		offers, err = g.s.GetPriceGrid(ctx, gflights.PriceGridArgs{
+			StartDepartureRange: from,
+			EndDepartureRange:   to,
+			StartReturnRange:    from.AddDate(0, 0, req.MinTripLength),
+			EndReturnRange:      to.AddDate(0, 0, req.MaxTripLength),
+			SrcCities:           srcCities,
+			SrcAirports:         srcAirports,
+			DstCities:           dstCities,
+			DstAirports:         dstAirports,
+			Options:             opts,
 		})
 	}
+	if err != nil {
+		return nil, err
+	}
 
-	return ei, nil
+	prices := make([]DatePrice, 0, len(offers))
+	for _, o := range offers {
+		p := DatePrice{DepartureDate: o.DepartureDate, Price: money.New(o.Price, req.Currency)}
+		if !req.OneWay() {
+			p.ReturnDate = o.ReturnDate
+		}
+		if o.Price > 0 && req.covers(p) {
+			prices = append(prices, p)
+		}
+	}
+	return prices, nil
+}
+
+// SearchURL returns a Google Flights url for the given request, which can be
+// used to follow up on an explore result.
+func (g *GFlights) SearchURL(ctx context.Context, req Request) (string, error) {
+	if err := req.Validate(); err != nil {
+		return "", err
+	}
+
+	srcCities, srcAirports := locations(req.Origin)
+	dstCities, dstAirports := locations(req.Destination)
+	return g.s.SerialiseURL(ctx, gflights.Args{
+		DepartureDate: req.DepartureDate,
+		ReturnDate:    req.ReturnDate,
+		SrcCities:     srcCities,
+		SrcAirports:   srcAirports,
+		DstCities:     dstCities,
+		DstAirports:   dstAirports,
+		Options: gflights.Options{
+			Travelers: req.travelers(),
+			Class:     gflights.Class(req.Class),
+			Currency:  req.Currency,
+			TripType:  gflights.RoundTrip,
+			Stops:     gflights.AnyStops,
+			Lang:      language.English,
+		},
+	})
 }
 
 func (g *GFlights) Search(
 	ctx context.Context,
 	req Request,
 ) ([]itinery.Itinery, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	oneWay := req.ReturnDate.IsZero()
+	tripType := gflights.RoundTrip
+	if oneWay {
+		tripType = gflights.OneWay
+	}
+
+	srcCities, srcAirports := locations(req.Origin)
+	dstCities, dstAirports := locations(req.Destination)
 	outboundFlights, _, err := g.s.GetOutboundOffers(ctx, gflights.Args{
 		DepartureDate: req.DepartureDate,
 		ReturnDate:    req.ReturnDate,
-		SrcCities:     []string{req.Origin},
-		DstCities:     []string{req.Destination},
+		SrcCities:     srcCities,
+		SrcAirports:   srcAirports,
+		DstCities:     dstCities,
+		DstAirports:   dstAirports,
 		Options: gflights.Options{
-			Travelers: gflights.Travelers{
-				Adults:   req.Adults,
-				Children: req.Children,
-			},
-			Class:    gflights.Class(req.Class),
-			Currency: req.Currency,
-			TripType: gflights.RoundTrip,
-			Stops:    gflights.AnyStops,
+			Travelers: req.travelers(),
+			Class:     gflights.Class(req.Class),
+			Currency:  req.Currency,
+			TripType:  tripType,
+			Stops:     gflights.AnyStops,
 		},
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	if oneWay {
+		return itinery.Dedupe(g.oneWayItineries(ctx, outboundFlights, req)), nil
+	}
+
 	// sort outboundFlights and lets choose top x
 	sort.Slice(outboundFlights, func(i, j int) bool {
 		return outboundFlights[i].Price < outboundFlights[j].Price
@@ -96,7 +268,11 @@ func (g *GFlights) Search(
 	wg := sync.WaitGroup{}
 	legsMu := sync.Mutex{}
 
-	capPrice := outboundFlights[5].Price
+	if len(outboundFlights) == 0 {
+		return itineries, nil
+	}
+
+	capPrice := outboundFlights[min(5, len(outboundFlights)-1)].Price
 
 	for i := 0; i < 5 && i < len(outboundFlights); i++ {
 		wg.Add(1)
@@ -112,35 +288,24 @@ func (g *GFlights) Search(
 				if rf.Price <= capPrice {
 					t, err := of.SelectReturnFlight(rf)
 					if err != nil {
-						fmt.Println("Error selecting return flight:", err)
-						continue
-					}
-					url, err := g.s.SerialiseBookingURL(ctx, t)
-					if err != nil {
-						fmt.Println("Error serialising booking URL:", err)
 						continue
 					}
+					// the fare is still worth showing without a link to book it.
+					url, _ := g.s.SerialiseBookingURL(ctx, t)
+
+					// gflights only gives the total for everyone, so the
+					// breakdown is an even split.
+					price := money.New(rf.Price, req.Currency)
 
 					legsMu.Lock()
 					itineries = append(itineries, itinery.Itinery{
-						Outbound: leg.Leg{
-							DepartureAirport: of.SrcAirportCode,
-							ArrivalAirport:   of.DstAirportCode,
-							DepartureTime:    of.DepartureDate,
-							ArrivalTime:      of.ReturnDate,
-							Stops:            len(of.Flight) - 1,
-							Flights:          gflightsFlightsToLegFlights(of.Flight),
-						},
-						Inbound: leg.Leg{
-							DepartureAirport: rf.Flight[0].DepAirportCode,
-							ArrivalAirport:   rf.Flight[len(rf.Flight)-1].ArrAirportCode,
-							DepartureTime:    rf.Flight[0].DepTime,
-							ArrivalTime:      rf.Flight[len(rf.Flight)-1].ArrTime,
-							Stops:            len(rf.Flight) - 1,
-							Flights:          gflightsFlightsToLegFlights(rf.Flight),
-						},
-						Price:      rf.Price,
+						Outbound:   gflightsFlightsToLeg(of.Flight, req.Class),
+						Inbound:    gflightsFlightsToLeg(rf.Flight, req.Class),
+						Price:      price,
+						Breakdown:  itinery.Split(price, req.passengers()),
+						Conditions: typicalConditions(of.Flight),
 						BookingURL: url,
+						ObservedAt: time.Now(),
 					})
 					legsMu.Unlock()
 				}
@@ -151,11 +316,125 @@ func (g *GFlights) Search(
 
 	wg.Wait()
 
-	return itineries, nil
+	return itinery.Dedupe(itineries), nil
+}
+
+// oneWayItineries turns outbound offers into itineries with no inbound leg,
+// there are no return flights to fetch so every offer is kept.
+func (g *GFlights) oneWayItineries(
+	ctx context.Context,
+	outboundFlights []gflights.OutboundOffer,
+	req Request,
+) []itinery.Itinery {
+	itineries := make([]itinery.Itinery, 0, len(outboundFlights))
+
+	for _, of := range outboundFlights {
+		if len(of.Flight) == 0 {
+			continue
+		}
+
+		// the fare is still worth showing without a link to book it.
+		url, _ := g.s.SerialiseBookingURL(ctx, of.SelectOneWay())
+
+		price := money.New(of.Price, req.Currency)
+		itineries = append(itineries, itinery.Itinery{
+			Outbound:   gflightsFlightsToLeg(of.Flight, req.Class),
+			Price:      price,
+			Breakdown:  itinery.Split(price, req.passengers()),
+			Conditions: typicalConditions(of.Flight),
+			BookingURL: url,
+			ObservedAt: time.Now(),
+		})
+	}
+
+	return itineries
+}
+
+// typicalConditions estimates a ticket's conditions from the bags the airline
+// selling its first flight includes in the baggage fee table, gflights doesn't
+// give fare rules.
+func typicalConditions(gfs []gflights.Flight) itinery.Conditions {
+	c := itinery.Conditions{Estimated: true}
+	if len(gfs) == 0 {
+		return c
+	}
+
+	code := gfs[0].FlightCode.AirlineCode
+	if a, ok := airline.Normalise(code, gfs[0].AirlineName); ok {
+		code = a.IATA
+	}
+	return baggage.For(code).Typical()
+}
+
+// locations splits a location into the cities or airports gflights expects,
+// hubs and explore results are airport codes while users tend to give cities.
+func locations(l string) (cities, airports []string) {
+	if len(l) == 3 && strings.ToUpper(l) == l {
+		return nil, []string{l}
+	}
+	return []string{l}, nil
+}
+
+func gflightsExploreOfferToExploreItinery(
+	offer gflights.ExploreOffer,
+	origin string,
+	t trip,
+	cur currency.Unit,
+) itinery.ExploreItinery {
+	ei := itinery.ExploreItinery{
+		Origin:        origin,
+		Destination:   offer.AirportCode,
+		DepartureDate: t.departure,
+		ReturnDate:    t.ret,
+		Stops:         offer.Stops,
+		MultiCarrier:  offer.IsMultiCarrier,
+		Price:         money.New(float64(offer.Price), cur),
+	}
+
+	// gflights gives the airline's name, codes are what the rest go by.
+	if !offer.IsMultiCarrier && offer.Airline != "" {
+		code := offer.Airline
+		if a, ok := airline.Normalise("", offer.Airline); ok {
+			code = a.IATA
+		}
+		ei.Airlines = []string{code}
+	}
+
+	if a, ok := airport.Lookup(offer.AirportCode); ok {
+		ei.City = a.City
+		ei.Country = a.Country
+	} else {
+		ei.City = iata.IATATimeZone(offer.AirportCode).City
+	}
+
+	if d, ok := duration(origin, offer.AirportCode); ok {
+		ei.Duration = d
+	}
+
+	return ei
 }
 
-func gflightsFlightToLegFlight(gf gflights.Flight) leg.Flight {
-	return leg.Flight{
+// duration estimates the flight time from origin, an airport or a city, to
+// the airport to. Cities are timed from the closest of their airports.
+func duration(origin, to string) (time.Duration, bool) {
+	if d, ok := airport.EstimateDuration(origin, to); ok {
+		return d, true
+	}
+
+	var shortest time.Duration
+	found := false
+	for _, a := range airport.InCity(origin) {
+		d, ok := airport.EstimateDuration(a.Code, to)
+		if ok && (!found || d < shortest) {
+			shortest, found = d, true
+		}
+	}
+	return shortest, found
+}
+
+// gflights doesn't say which cabin a flight is in, it's the one searched for.
+func gflightsFlightToLegFlight(gf gflights.Flight, class Class) leg.Flight {
+	f := leg.Flight{
 		DepartureTime:    gf.DepTime,
 		ArrivalTime:      gf.ArrTime,
 		DepartureAirport: gf.DepAirportCode,
@@ -163,15 +442,73 @@ func gflightsFlightToLegFlight(gf gflights.Flight) leg.Flight {
 		FlightCode:       gf.FlightCode.AirlineCode + gf.FlightCode.FlightNumber,
 		Plane:            gf.Airplane,
 		Airline:          gf.AirlineName,
+		AirlineCode:      gf.FlightCode.AirlineCode,
+		Cabin:            class,
+	}
+
+	// google's names vary, use the dataset's so flights on the same airline
+	// match.
+	if a, ok := airline.Normalise(f.AirlineCode, f.Airline); ok {
+		f.Airline, f.AirlineCode = a.Name, a.IATA
 	}
+
+	if a, ok := aircraft.Lookup(f.Plane); ok {
+		f.Aircraft = a.Family
+	}
+
+	if name := operator(gf); name != "" {
+		op, ok := airline.ByName(name)
+		switch {
+		case !ok:
+			f.OperatingAirline = name
+		case op.IATA != f.AirlineCode:
+			f.OperatingAirline, f.OperatingAirlineCode = op.Name, op.IATA
+		}
+	}
+	return f
+}
+
+// operator is who flies gf when google says it's someone other than the
+// airline selling it. gflights doesn't parse it, it's the third field of the
+// raw flight, such as "Operated by SkyWest DBA United Express".
+func operator(gf gflights.Flight) string {
+	if len(gf.Unknown) < 3 {
+		return ""
+	}
+	s, ok := gf.Unknown[2].(string)
+	if !ok {
+		return ""
+	}
+	s = strings.TrimSpace(s)
+	if len(s) > len("operated by ") && strings.EqualFold(s[:len("operated by ")], "operated by ") {
+		s = s[len("operated by "):]
+	}
+	if strings.EqualFold(s, gf.AirlineName) {
+		return ""
+	}
+	return s
 }
 
-func gflightsFlightsToLegFlights(gfs []gflights.Flight) []leg.Flight {
+func gflightsFlightsToLeg(gfs []gflights.Flight, class Class) leg.Leg {
+	return leg.Leg{
+		DepartureAirport: gfs[0].DepAirportCode,
+		ArrivalAirport:   gfs[len(gfs)-1].ArrAirportCode,
+		DepartureTime:    gfs[0].DepTime,
+		ArrivalTime:      gfs[len(gfs)-1].ArrTime,
+		Stops:            len(gfs) - 1,
+		Flights:          gflightsFlightsToLegFlights(gfs, class),
+	}
+}
+
+func gflightsFlightsToLegFlights(gfs []gflights.Flight, class Class) []leg.Flight {
 	flights := make([]leg.Flight, len(gfs))
 	for i, gf := range gfs {
-		flights[i] = gflightsFlightToLegFlight(gf)
+		flights[i] = gflightsFlightToLegFlight(gf, class)
 	}
 	return flights
 }
 
-var _ Provider = (*GFlights)(nil)
+var (
+	_ Provider   = (*GFlights)(nil)
+	_ DatePricer = (*GFlights)(nil)
+)
@@ -5,9 +5,69 @@ import (
 	"testing"
 	"time"
 
+	"github.com/tobyrushton/flyvia/packages/search/baggage"
+	"github.com/tobyrushton/gflights"
 	"golang.org/x/text/currency"
 )
 
+func TestGFlightsFlightOperator(t *testing.T) {
+	gf := gflights.Flight{
+		FlightCode:  gflights.FlightCode{AirlineCode: "AA", FlightNumber: "6132"},
+		AirlineName: "American",
+		Unknown:     []any{nil, nil, "Operated by Iberia"},
+	}
+
+	f := gflightsFlightToLegFlight(gf, Economy)
+	if f.Airline != "American Airlines" || f.OperatingAirline != "Iberia" || f.OperatingAirlineCode != "IB" {
+		t.Errorf("expected American Airlines operated by Iberia, got %+v", f)
+	}
+
+	gf.Unknown[2] = "Operated by American Airlines"
+	if f := gflightsFlightToLegFlight(gf, Economy); f.Codeshare() {
+		t.Errorf("expected a flight operated by its own airline not to be a codeshare, got %+v", f)
+	}
+
+	gf.Unknown[2] = "Operated by Skywest DBA American Eagle"
+	if f := gflightsFlightToLegFlight(gf, Economy); f.OperatingAirline != "Skywest DBA American Eagle" {
+		t.Errorf("expected an unknown operator to be kept as given, got %q", f.OperatingAirline)
+	}
+}
+
+func TestTypicalConditions(t *testing.T) {
+	c := typicalConditions([]gflights.Flight{{FlightCode: gflights.FlightCode{AirlineCode: "FR"}}})
+	if !c.Estimated || c.CarryOn || c.CheckedBags != 0 || c.Flexible() {
+		t.Errorf("expected Ryanair to include only a personal item, got %+v", c)
+	}
+
+	c = typicalConditions([]gflights.Flight{{FlightCode: gflights.FlightCode{AirlineCode: "QR"}}})
+	if !c.CarryOn || c.CheckedBags != 1 {
+		t.Errorf("expected Qatar to include a checked bag, got %+v", c)
+	}
+
+	c = typicalConditions([]gflights.Flight{{FlightCode: gflights.FlightCode{AirlineCode: "DY"}}})
+	if c.CarryOn != (baggage.For("DY").CarryOnIncluded > 0) {
+		t.Errorf("expected Norwegian's cabin bag to match its baggage fees, got %+v", c)
+	}
+
+	// a name that can't be normalised falls back to the flight's code.
+	c = typicalConditions([]gflights.Flight{{FlightCode: gflights.FlightCode{AirlineCode: "FR"}, AirlineName: "Unheard Of Air"}})
+	if c.CarryOn || c.CheckedBags != 0 {
+		t.Errorf("expected Ryanair's allowance from its code, got %+v", c)
+	}
+}
+
+func TestGFlightsExploreOffer(t *testing.T) {
+	offer := gflights.ExploreOffer{AirportCode: "MAD", Airline: "Iberia", Price: 80}
+
+	ei := gflightsExploreOfferToExploreItinery(offer, "London", trip{}, currency.GBP)
+	if len(ei.Airlines) != 1 || ei.Airlines[0] != "IB" {
+		t.Errorf("expected the airline's name to be normalised to IB, got %v", ei.Airlines)
+	}
+	if ei.Duration == 0 {
+		t.Error("expected a duration from a city origin")
+	}
+}
+
 func TestGFlightsSearch(t *testing.T) {
 	provider, err := NewGFlights()
 	if err != nil {
@@ -21,7 +81,7 @@ func TestGFlightsSearch(t *testing.T) {
 			Destination:   "NEW YORK",
 			DepartureDate: time.Now().Add(time.Hour * 24),
 			ReturnDate:    time.Now().Add(time.Hour * 24 * 7),
-			Adults:        1,
+			Party:         Party{Adults: 1},
 			Class:         Economy,
 			Currency:      currency.GBP,
 		},
@@ -43,15 +103,15 @@ func TestGFlightsExplore(t *testing.T) {
 
 	itineries, err := provider.Explore(
 		context.Background(),
-		Request{
-			Origin:        "LONDON",
-			DepartureDate: time.Now().Add(time.Hour * 24),
-			ReturnDate:    time.Now().Add(time.Hour * 24 * 7),
-			Adults:        1,
+		ExploreRequest{
+			Origins:       []string{"London"},
+			DepartureFrom: time.Now().Add(time.Hour * 24),
+			MinTripLength: 6,
+			MaxTripLength: 6,
+			Party:         Party{Adults: 1},
 			Class:         Economy,
 			Currency:      currency.GBP,
 		},
-		"London",
 	)
 	if err != nil {
 		t.Fatal(err)
@@ -9,8 +9,7 @@ import (
 type Provider interface {
 	Explore(
 		ctx context.Context,
-		req Request,
-		origin string,
+		req ExploreRequest,
 	) ([]itinery.ExploreItinery, error)
 	Search(
 		ctx context.Context,
@@ -0,0 +1,61 @@
+// Package providertest has a provider.Provider for tests of the searches
+// built on one.
+package providertest
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/provider"
+)
+
+// Provider answers explores and searches with its funcs, counting the calls
+// made to each. A nil func finds nothing. Calls can be made concurrently, so
+// the funcs must be safe for that when the code under test makes them.
+type Provider struct {
+	ExploreFunc func(ctx context.Context, req provider.ExploreRequest) ([]itinery.ExploreItinery, error)
+	SearchFunc  func(ctx context.Context, req provider.Request) ([]itinery.Itinery, error)
+
+	explores atomic.Int32
+	searches atomic.Int32
+}
+
+func (p *Provider) Explore(
+	ctx context.Context,
+	req provider.ExploreRequest,
+) ([]itinery.ExploreItinery, error) {
+	p.explores.Add(1)
+	if p.ExploreFunc == nil {
+		return []itinery.ExploreItinery{}, nil
+	}
+	return p.ExploreFunc(ctx, req)
+}
+
+func (p *Provider) Search(
+	ctx context.Context,
+	req provider.Request,
+) ([]itinery.Itinery, error) {
+	p.searches.Add(1)
+	if p.SearchFunc == nil {
+		return []itinery.Itinery{}, nil
+	}
+	return p.SearchFunc(ctx, req)
+}
+
+// Explores is the number of explores made.
+func (p *Provider) Explores() int {
+	return int(p.explores.Load())
+}
+
+// Searches is the number of searches made.
+func (p *Provider) Searches() int {
+	return int(p.searches.Load())
+}
+
+// Calls is the number of explores and searches made.
+func (p *Provider) Calls() int {
+	return p.Explores() + p.Searches()
+}
+
+var _ provider.Provider = (*Provider)(nil)
@@ -1,20 +1,46 @@
 package provider
 
 import (
+	"fmt"
+	"slices"
 	"time"
 
+	"github.com/tobyrushton/flyvia/packages/search/baggage"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/gflights"
 	"golang.org/x/text/currency"
 )
 
-type Class int64
+// Class is a leg.Cabin so the class searched for can be reported on each
+// flight.
+type Class = leg.Cabin
 
 const (
-	Economy Class = iota + 1
-	PremiumEconomy
-	Business
-	First
+	Economy        = leg.Economy
+	PremiumEconomy = leg.PremiumEconomy
+	Business       = leg.Business
+	First          = leg.First
 )
 
+// the most passengers that can be booked on one ticket.
+const maxPassengers = 9
+
+// Party is who is travelling, every kind of request carries one.
+type Party struct {
+	Adults   int
+	Children int
+	// ages of the children, 2 to 11, in any order. Optional, when given there
+	// must be one per child.
+	ChildAges     []int
+	InfantsInSeat int
+	InfantsOnLap  int
+	// youths (12-17) and seniors are booked as adults, seniors can take a lap
+	// infant but youths can't.
+	Youths  int
+	Seniors int
+}
+
 type Request struct {
 	Origin      string
 	Destination string
@@ -22,9 +48,146 @@ type Request struct {
 	DepartureDate time.Time
 	ReturnDate    time.Time
 
-	Adults   int
-	Children int
+	Party
+
+	// ISO 3166-1 alpha-2 codes of each traveller's passports, used to check
+	// entry requirements at stops. A traveller with several lists them all.
+	Nationalities [][]string
+
+	// what each passenger is bringing, lap infants don't get an allowance.
+	Bags baggage.Bags
 
 	Currency currency.Unit
 	Class    Class
 }
+
+// Validate checks the passengers can be booked together.
+func (r Party) Validate() error {
+	counts := []int{r.Adults, r.Children, r.InfantsInSeat, r.InfantsOnLap, r.Youths, r.Seniors}
+	for _, n := range counts {
+		if n < 0 {
+			return fmt.Errorf("passenger counts cannot be negative")
+		}
+	}
+
+	// youths are booked as adults so can travel on their own, but can't be
+	// responsible for an infant.
+	if r.Adults+r.Youths+r.Seniors == 0 {
+		return fmt.Errorf("at least one adult, youth or senior is required")
+	}
+	grownUps := r.Adults + r.Seniors
+	if n := r.Passengers(); n > maxPassengers {
+		return fmt.Errorf("at most %d passengers can be booked together, got %d", maxPassengers, n)
+	}
+	if r.InfantsOnLap > grownUps {
+		return fmt.Errorf("each lap infant needs an adult, got %d infants for %d adults", r.InfantsOnLap, grownUps)
+	}
+	// gflights allows two infants per adult, one on their lap and one in a seat.
+	if r.InfantsOnLap+r.InfantsInSeat > 2*grownUps {
+		return fmt.Errorf("at most two infants per adult, got %d infants for %d adults", r.InfantsOnLap+r.InfantsInSeat, grownUps)
+	}
+
+	if len(r.ChildAges) > 0 && len(r.ChildAges) != r.Children {
+		return fmt.Errorf("got %d child ages for %d children", len(r.ChildAges), r.Children)
+	}
+	for _, age := range r.ChildAges {
+		if age < 2 || age > 11 {
+			return fmt.Errorf("child age %d out of range, children are 2 to 11", age)
+		}
+	}
+
+	return nil
+}
+
+// Key identifies the search, requests with the same key give the same
+// results.
+func (r Request) Key() string {
+	return fmt.Sprintf(
+		"%s|%s|%s|%s|%s|%s|%d",
+		r.Origin,
+		r.Destination,
+		r.DepartureDate.Format(time.DateOnly),
+		r.ReturnDate.Format(time.DateOnly),
+		r.Party.key(),
+		r.Currency,
+		r.Class,
+	)
+}
+
+// TripLength is the number of days between departure and return, zero for
+// one-way trips.
+func (r Request) TripLength() int {
+	if r.ReturnDate.IsZero() {
+		return 0
+	}
+	return Days(r.DepartureDate, r.ReturnDate)
+}
+
+// Days is the number of calendar days from from to to, counted on their
+// dates so a change of clocks in between doesn't lose one.
+func Days(from, to time.Time) int {
+	y, m, d := from.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	y, m, d = to.Date()
+	end := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return int(end.Sub(start).Hours() / 24)
+}
+
+// key identifies the party, child ages in any order are the same party.
+func (r Party) key() string {
+	ages := slices.Sorted(slices.Values(r.ChildAges))
+	return fmt.Sprintf(
+		"%d|%d|%v|%d|%d|%d|%d",
+		r.Adults,
+		r.Children,
+		ages,
+		r.InfantsInSeat,
+		r.InfantsOnLap,
+		r.Youths,
+		r.Seniors,
+	)
+}
+
+// Passengers is the number of people travelling, including lap infants.
+func (r Party) Passengers() int {
+	return r.Adults + r.Children + r.InfantsInSeat + r.InfantsOnLap + r.Youths + r.Seniors
+}
+
+// Seated is the number of passengers with a seat, everyone bar lap infants.
+func (r Party) Seated() int {
+	return r.Passengers() - r.InfantsOnLap
+}
+
+// Infants is the number of infants, on a lap or in a seat.
+func (r Party) Infants() int {
+	return r.InfantsInSeat + r.InfantsOnLap
+}
+
+// YoungestChild is the age of the youngest child, false when there are no
+// children or their ages weren't given.
+func (r Party) YoungestChild() (int, bool) {
+	if len(r.ChildAges) == 0 {
+		return 0, false
+	}
+	return slices.Min(r.ChildAges), true
+}
+
+func (r Party) travelers() gflights.Travelers {
+	return gflights.Travelers{
+		Adults:        r.Adults + r.Youths + r.Seniors,
+		Children:      r.Children,
+		InfantsInSeat: r.InfantsInSeat,
+		InfantsOnLap:  r.InfantsOnLap,
+	}
+}
+
+func (r Party) passengers() map[itinery.PassengerType]int {
+	return map[itinery.PassengerType]int{
+		itinery.Adult:        r.Adults,
+		itinery.Youth:        r.Youths,
+		itinery.Senior:       r.Seniors,
+		itinery.Child:        r.Children,
+		itinery.InfantInSeat: r.InfantsInSeat,
+		itinery.InfantOnLap:  r.InfantsOnLap,
+	}
+}
@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+)
+
+func TestRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     Request
+		wantErr bool
+	}{
+		{"single adult", Request{Party: Party{Adults: 1}}, false},
+		{"family", Request{Party: Party{Adults: 2, Children: 2, ChildAges: []int{4, 9}, InfantsOnLap: 1}}, false},
+		{"senior with lap infant", Request{Party: Party{Seniors: 1, InfantsOnLap: 1}}, false},
+		{"no adults", Request{Party: Party{Children: 1}}, true},
+		{"only youths", Request{Party: Party{Youths: 2}}, false},
+		{"too many", Request{Party: Party{Adults: 6, Children: 4}}, true},
+		{"lap infants over adults", Request{Party: Party{Adults: 1, InfantsOnLap: 2}}, true},
+		{"lap infant for a youth", Request{Party: Party{Adults: 1, Youths: 1, InfantsOnLap: 2}}, true},
+		{"three infants per adult", Request{Party: Party{Adults: 1, InfantsOnLap: 1, InfantsInSeat: 2}}, true},
+		{"missing child ages", Request{Party: Party{Adults: 1, Children: 2, ChildAges: []int{5}}}, true},
+		{"child age of an infant", Request{Party: Party{Adults: 1, Children: 1, ChildAges: []int{1}}}, true},
+		{"negative", Request{Party: Party{Adults: 1, Children: -1}}, true},
+	}
+
+	for _, tt := range tests {
+		err := tt.req.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestRequestTravelers(t *testing.T) {
+	req := Request{Party: Party{Adults: 1, Youths: 1, Seniors: 1, Children: 1, InfantsInSeat: 1, InfantsOnLap: 1}}
+
+	tr := req.travelers()
+	if tr.Adults != 3 {
+		t.Errorf("expected youths and seniors to be booked as adults, got %d adults", tr.Adults)
+	}
+	if err := tr.Validate(); err != nil {
+		t.Errorf("expected valid gflights travelers, got %v", err)
+	}
+
+	if n := req.passengers()[itinery.InfantOnLap]; n != 1 {
+		t.Errorf("expected 1 lap infant in the breakdown, got %d", n)
+	}
+}
+
+func TestRequestTripLength(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	// the clocks go forward on the 31st, leaving 71 hours between them.
+	req := Request{
+		DepartureDate: time.Date(2030, 3, 30, 0, 0, 0, 0, london),
+		ReturnDate:    time.Date(2030, 4, 2, 0, 0, 0, 0, london),
+	}
+	if n := req.TripLength(); n != 3 {
+		t.Errorf("expected a 3 day trip across the change of clocks, got %d", n)
+	}
+	if n := (Request{DepartureDate: req.DepartureDate}).TripLength(); n != 0 {
+		t.Errorf("expected one-way trips to have no length, got %d", n)
+	}
+}
+
+func TestDatesRequestCovers(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2030, 2, d, 0, 0, 0, 0, time.UTC) }
+	req := DatesRequest{DepartureFrom: day(10), DepartureTo: day(12), MinTripLength: 3, MaxTripLength: 4}
+
+	tests := []struct {
+		name string
+		p    DatePrice
+		want bool
+	}{
+		{"first day", DatePrice{DepartureDate: day(10), ReturnDate: day(13)}, true},
+		{"last day, longest trip", DatePrice{DepartureDate: day(12), ReturnDate: day(16)}, true},
+		{"before the range", DatePrice{DepartureDate: day(9), ReturnDate: day(12)}, false},
+		{"after the range", DatePrice{DepartureDate: day(13), ReturnDate: day(16)}, false},
+		{"too long", DatePrice{DepartureDate: day(10), ReturnDate: day(15)}, false},
+		{"too short", DatePrice{DepartureDate: day(10), ReturnDate: day(12)}, false},
+	}
+
+	for _, tt := range tests {
+		if got := req.covers(tt.p); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
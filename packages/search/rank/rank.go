@@ -0,0 +1,90 @@
+package rank
+
+import (
+	"math"
+	"sort"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/aircraft"
+	"github.com/tobyrushton/flyvia/packages/search/airline"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+)
+
+// Key is what results are ranked on, lowest first.
+type Key func(r search.Result) float64
+
+// Sort orders results by key, keeping the existing order for ties. Keys
+// compare prices as plain numbers, so results priced in different currencies
+// can't be ranked and are left as they are.
+func Sort(results []search.Result, key Key) error {
+	for _, r := range results {
+		for _, price := range []money.Money{r.Price, r.RiskAdjustedPrice} {
+			if _, err := price.Cmp(results[0].Price); err != nil {
+				return err
+			}
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return key(results[i]) < key(results[j])
+	})
+	return nil
+}
+
+func ByPrice(r search.Result) float64 {
+	return r.Price.Float64()
+}
+
+// ByRisk ranks on the risk-adjusted price, falling back to the total for
+// results that haven't been assessed.
+func ByRisk(r search.Result) float64 {
+	if r.RiskAdjustedPrice.IsZero() {
+		return ByTotal(r)
+	}
+	return r.RiskAdjustedPrice.Float64()
+}
+
+// ByPricePerPoint ranks on what each frequent flyer point costs, results
+// earning none go last.
+func ByPricePerPoint(r search.Result) float64 {
+	points := r.TotalPoints()
+	if points == 0 {
+		return math.Inf(1)
+	}
+	return ByTotal(r) / float64(points)
+}
+
+// ByCO2 ranks the greenest first, results without an estimate go last.
+func ByCO2(r search.Result) float64 {
+	if r.CO2 == 0 {
+		return math.Inf(1)
+	}
+	return r.CO2
+}
+
+// PreferAlliance ranks on key with results discounted by up to weight, in
+// proportion to how many of their flights are in alliance. A weight of 0.1
+// ranks a result flown entirely in the alliance as if it were 10% cheaper.
+func PreferAlliance(key Key, alliance airline.Alliance, weight float64) Key {
+	return func(r search.Result) float64 {
+		return key(r) * (1 - weight*airline.Share(r, alliance))
+	}
+}
+
+// PreferAircraft ranks on key with results discounted by up to weight, in
+// proportion to how many of the flights p applies to are on its aircraft.
+func PreferAircraft(key Key, p aircraft.Preference, weight float64) Key {
+	return func(r search.Result) float64 {
+		return key(r) * (1 - weight*p.Share(r))
+	}
+}
+
+// ByTotal ranks on the price including bags, results whose bag fees can't be
+// added to the price go last.
+func ByTotal(r search.Result) float64 {
+	total, err := r.Total()
+	if err != nil {
+		return math.Inf(1)
+	}
+	return total.Float64()
+}
@@ -0,0 +1,129 @@
+package rank
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/aircraft"
+	"github.com/tobyrushton/flyvia/packages/search/airline"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"github.com/tobyrushton/flyvia/packages/search/money/moneytest"
+	"golang.org/x/text/currency"
+)
+
+func TestSort(t *testing.T) {
+	results := []search.Result{
+		{StopCity: "DUB", Price: moneytest.GBP(300), BagFees: moneytest.GBP(0)},
+		{StopCity: "STN", Price: moneytest.GBP(250), BagFees: moneytest.GBP(80)},
+		{StopCity: "AMS", Price: moneytest.GBP(300), BagFees: moneytest.GBP(20)},
+	}
+
+	if err := Sort(results, ByPrice); err != nil {
+		t.Fatal(err)
+	}
+	if results[0].StopCity != "STN" || results[1].StopCity != "DUB" {
+		t.Errorf("expected STN then DUB keeping order for the tie, got %s then %s", results[0].StopCity, results[1].StopCity)
+	}
+
+	if err := Sort(results, ByTotal); err != nil {
+		t.Fatal(err)
+	}
+	if results[0].StopCity != "DUB" || results[2].StopCity != "STN" {
+		t.Errorf("expected bags to push STN last, got %s first and %s last", results[0].StopCity, results[2].StopCity)
+	}
+}
+
+func TestSort_CurrencyMismatch(t *testing.T) {
+	results := []search.Result{
+		{StopCity: "DUB", Price: moneytest.GBP(300)},
+		{StopCity: "AMS", Price: money.New(250, currency.EUR)},
+	}
+
+	if err := Sort(results, ByPrice); !errors.Is(err, money.ErrCurrencyMismatch) {
+		t.Errorf("expected ErrCurrencyMismatch, got %v", err)
+	}
+	if results[0].StopCity != "DUB" {
+		t.Error("expected results in another currency to be left as they were")
+	}
+}
+
+func TestByRisk(t *testing.T) {
+	results := []search.Result{
+		{StopCity: "DUB", Price: moneytest.GBP(300), RiskAdjustedPrice: moneytest.GBP(360)},
+		{StopCity: "AMS", Price: moneytest.GBP(320)},
+	}
+
+	if err := Sort(results, ByRisk); err != nil {
+		t.Fatal(err)
+	}
+	if results[0].StopCity != "AMS" {
+		t.Errorf("expected the unassessed AMS to rank on its total of 320, got %s first", results[0].StopCity)
+	}
+}
+
+func TestByCO2(t *testing.T) {
+	results := []search.Result{
+		{StopCity: "DUB"},
+		{StopCity: "AMS", CO2: 640},
+		{StopCity: "CDG", CO2: 590},
+	}
+
+	if err := Sort(results, ByCO2); err != nil {
+		t.Fatal(err)
+	}
+	if results[0].StopCity != "CDG" || results[2].StopCity != "DUB" {
+		t.Errorf("expected CDG first and the unestimated DUB last, got %s first and %s last", results[0].StopCity, results[2].StopCity)
+	}
+}
+
+func TestPreferAlliance(t *testing.T) {
+	flown := func(code string) []itinery.Itinery {
+		return []itinery.Itinery{{Outbound: leg.Leg{Flights: []leg.Flight{{AirlineCode: code}}}}}
+	}
+	results := []search.Result{
+		{StopCity: "DXB", Price: moneytest.GBP(500), Itineries: flown("EK")},
+		{StopCity: "DOH", Price: moneytest.GBP(520), Itineries: flown("QR")},
+	}
+
+	if err := Sort(results, PreferAlliance(ByPrice, airline.Oneworld, 0.1)); err != nil {
+		t.Fatal(err)
+	}
+	if results[0].StopCity != "DOH" {
+		t.Errorf("expected oneworld DOH to rank ahead of the slightly cheaper DXB, got %s first", results[0].StopCity)
+	}
+}
+
+func TestPreferAircraft(t *testing.T) {
+	flown := func(plane string) []itinery.Itinery {
+		return []itinery.Itinery{{Outbound: leg.Leg{Flights: []leg.Flight{{Plane: plane}}}}}
+	}
+	results := []search.Result{
+		{StopCity: "AMS", Price: moneytest.GBP(100), Itineries: flown("ATR 72")},
+		{StopCity: "DUB", Price: moneytest.GBP(104), Itineries: flown("Airbus A320")},
+	}
+
+	if err := Sort(results, PreferAircraft(ByPrice, aircraft.Preference{Aircraft: "narrowbody"}, 0.05)); err != nil {
+		t.Fatal(err)
+	}
+	if results[0].StopCity != "DUB" {
+		t.Errorf("expected the jet to DUB to rank ahead of the cheaper turboprop, got %s first", results[0].StopCity)
+	}
+}
+
+func TestByPricePerPoint(t *testing.T) {
+	results := []search.Result{
+		{StopCity: "DUB", Price: moneytest.GBP(300)},
+		{StopCity: "AMS", Price: moneytest.GBP(400), Points: map[string]int{"FB": 2000}},
+		{StopCity: "DOH", Price: moneytest.GBP(600), Points: map[string]int{"BAEC": 2500, "QR": 1500}},
+	}
+
+	if err := Sort(results, ByPricePerPoint); err != nil {
+		t.Fatal(err)
+	}
+	if results[0].StopCity != "DOH" || results[2].StopCity != "DUB" {
+		t.Errorf("expected DOH at 15p a point first and DUB earning nothing last, got %s first and %s last", results[0].StopCity, results[2].StopCity)
+	}
+}
@@ -4,18 +4,89 @@ import (
 	"time"
 
 	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money"
 )
 
 type Result struct {
 	StopCity    string
 	StopLengths []time.Duration
 	Itineries   []itinery.Itinery
-	Price       float64
+	Price       money.Money
+
+	// what bags cost on top of Price, set by baggage.Apply.
+	BagFees money.Money
+	// checked bags have to be collected and checked in again between tickets.
+	RecollectBags bool
+
+	// the changes between tickets, set by Classify.
+	Connections []Connection
+	// hotels for overnight connections.
+	HotelCost money.Money
+
+	// chance of missing any connection between tickets, set by risk.Assess.
+	MissProbability float64
+	// Total plus the expected cost of replacing missed tickets.
+	RiskAdjustedPrice money.Money
+
+	// kg of CO2e per passenger, and of flying direct from the origin to the
+	// destination to compare it to, set by emissions.Apply.
+	CO2       float64
+	DirectCO2 float64
+
+	// frequent flyer points and status credits by programme, set by
+	// loyalty.Earn.
+	Points        map[string]int
+	StatusCredits map[string]int
+
+	// when the prices were seen, the earliest of its tickets.
+	ObservedAt time.Time
+	// set by via.Searcher.Verify, the tickets were priced again at VerifiedAt
+	// and are all still sold.
+	Verified   bool
+	VerifiedAt time.Time
+	// what the price moved by when verified, and whether any ticket's flights
+	// are no longer sold.
+	PriceChange money.Money
+	SoldOut     bool
+
+	// things to check before booking, such as visas needed at a stop.
+	Warnings []string
+}
+
+// Total is what the trip costs including bags and hotels.
+func (r Result) Total() (money.Money, error) {
+	return money.Sum(r.Price, r.BagFees, r.HotelCost)
+}
+
+// TotalPoints is the points earned across every programme.
+func (r Result) TotalPoints() int {
+	total := 0
+	for _, p := range r.Points {
+		total += p
+	}
+	return total
+}
+
+// Flights are every flight of every ticket.
+func (r Result) Flights() []leg.Flight {
+	flights := make([]leg.Flight, 0)
+	for _, itin := range r.Itineries {
+		flights = append(flights, itin.Outbound.Flights...)
+		flights = append(flights, itin.Inbound.Flights...)
+	}
+	return flights
 }
 
+// NewResult fails if the itineries are priced in different currencies.
 func NewResult(
 	itinery1, itinery2 itinery.Itinery,
-) Result {
+) (Result, error) {
+	price, err := itinery1.Price.Add(itinery2.Price)
+	if err != nil {
+		return Result{}, err
+	}
+
 	return Result{
 		StopCity:  itinery1.Outbound.ArrivalAirport,
 		Itineries: []itinery.Itinery{itinery1, itinery2},
@@ -23,6 +94,62 @@ func NewResult(
 			itinery2.Outbound.DepartureTime.Sub(itinery1.Outbound.ArrivalTime),
 			itinery1.Inbound.DepartureTime.Sub(itinery2.Inbound.ArrivalTime),
 		},
-		Price: itinery1.Price + itinery2.Price,
+		Price:      price,
+		ObservedAt: observedAt(itinery1, itinery2),
+	}, nil
+}
+
+// Breakdown totals what each passenger type pays across every ticket, the
+// per-ticket breakdowns are on the itineries.
+func (r Result) Breakdown() (itinery.Breakdown, error) {
+	b := itinery.Breakdown{}
+	for _, itin := range r.Itineries {
+		var err error
+		if b, err = b.Merge(itin.Breakdown); err != nil {
+			return itinery.Breakdown{}, err
+		}
+	}
+	return b, nil
+}
+
+// NewChain joins one-way tickets flown one after another, such as the legs of
+// a tour. StopCity is the first stop and StopLengths the time between tickets.
+// It fails if the tickets are priced in different currencies.
+func NewChain(itineries ...itinery.Itinery) (Result, error) {
+	r := Result{
+		Itineries:   itineries,
+		StopLengths: make([]time.Duration, 0, len(itineries)),
+	}
+
+	for i, itin := range itineries {
+		var err error
+		if r.Price, err = r.Price.Add(itin.Price); err != nil {
+			return Result{}, err
+		}
+		if i == 0 {
+			continue
+		}
+		r.StopLengths = append(
+			r.StopLengths,
+			itin.Outbound.DepartureTime.Sub(itineries[i-1].Outbound.ArrivalTime),
+		)
+	}
+
+	if len(itineries) > 1 {
+		r.StopCity = itineries[0].Outbound.ArrivalAirport
+	}
+	r.ObservedAt = observedAt(itineries...)
+
+	return r, nil
+}
+
+// observedAt is when the stalest of itineries was priced.
+func observedAt(itineries ...itinery.Itinery) time.Time {
+	var earliest time.Time
+	for _, itin := range itineries {
+		if !itin.ObservedAt.IsZero() && (earliest.IsZero() || itin.ObservedAt.Before(earliest)) {
+			earliest = itin.ObservedAt
+		}
 	}
+	return earliest
 }
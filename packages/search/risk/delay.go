@@ -0,0 +1,59 @@
+package risk
+
+import (
+	"math"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/airline"
+	"github.com/tobyrushton/flyvia/packages/search/airport"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+)
+
+// DelaySource gives the chance of a flight arriving more than the duration
+// late behind schedule, false when it knows nothing about the flight.
+type DelaySource interface {
+	ProbLate(f leg.Flight, late time.Duration) (float64, bool)
+}
+
+// a rough fit to published arrival delay figures, most flights are on time
+// and the chance of a delay falls away quickly with its length. Cancellations
+// are a floor no layover protects against.
+const (
+	probDelayed   = 0.35
+	meanDelay     = 40 * time.Minute
+	probCancelled = 0.015
+	longHaulKm    = 4000
+	eveningHour   = 17
+	morningHour   = 10
+)
+
+// Heuristic estimates delays from the airline, route and time of day when
+// there's no recorded data for a flight.
+type Heuristic struct{}
+
+func (Heuristic) ProbLate(f leg.Flight, late time.Duration) (float64, bool) {
+	p := probDelayed * math.Exp(-late.Minutes()/meanDelay.Minutes())
+
+	// low-cost carriers run tighter turnarounds, so delays carry through the
+	// day.
+	if a, ok := airline.Of(f); ok && a.LowCost {
+		p *= 1.2
+	}
+
+	// delays build up over the day, the first flights out are the most
+	// punctual.
+	switch hour := f.DepartureTime.Hour(); {
+	case hour >= eveningHour:
+		p *= 1.3
+	case hour < morningHour:
+		p *= 0.8
+	}
+
+	if km, ok := airport.Distance(f.DepartureAirport, f.ArrivalAirport); ok && km > longHaulKm {
+		p *= 1.1
+	}
+
+	return min(1, p+probCancelled), true
+}
+
+var _ DelaySource = Heuristic{}
@@ -0,0 +1,91 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/airport"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"github.com/tobyrushton/flyvia/packages/search/provider"
+	"github.com/tobyrushton/flyvia/packages/search/via"
+)
+
+// Cached rebooks with the tickets a via.Searcher has already searched, so
+// replacing the onward ticket from a hub is usually priced without another
+// search.
+type Cached struct {
+	s   *via.Searcher
+	req provider.Request
+}
+
+// NewCached rebooks trips found by s for req.
+func NewCached(s *via.Searcher, req provider.Request) *Cached {
+	return &Cached{s: s, req: req}
+}
+
+// Rebook prices the cheapest ticket leaving after the missed one, that day or
+// the next. A missed onward ticket is replaced with the same ticket later, a
+// missed flight home with a one-way.
+func (c *Cached) Rebook(ctx context.Context, from, to string, after time.Time) (money.Money, error) {
+	req := c.req
+	req.Origin = from
+
+	// searched as the request gave them so the cached tickets are found.
+	switch {
+	case matches(c.req.Destination, to):
+		req.Destination = c.req.Destination
+	case matches(c.req.Origin, to):
+		req.Destination = c.req.Origin
+		req.ReturnDate = time.Time{}
+	default:
+		req.Destination = to
+	}
+
+	day := time.Date(after.Year(), after.Month(), after.Day(), 0, 0, 0, 0, time.UTC)
+	for i := range 2 {
+		req.DepartureDate = day.AddDate(0, 0, i)
+
+		itins, err := c.s.Ticket(ctx, req)
+		if err != nil {
+			return money.Money{}, err
+		}
+
+		if cheapest, ok := cheapestAfter(itins, after); ok {
+			return cheapest, nil
+		}
+	}
+
+	return money.Money{}, fmt.Errorf("%w from %s to %s after %s", ErrNoReplacement, from, to, after.Format(time.DateTime))
+}
+
+func cheapestAfter(itins []itinery.Itinery, after time.Time) (money.Money, bool) {
+	var cheapest money.Money
+	found := false
+	for _, itin := range itins {
+		if !itin.Outbound.DepartureTime.After(after) {
+			continue
+		}
+		if found {
+			if c, err := itin.Price.Cmp(cheapest); err != nil || c >= 0 {
+				continue
+			}
+		}
+		cheapest, found = itin.Price, true
+	}
+	return cheapest, found
+}
+
+// matches reports whether an airport is the location, given as an airport or
+// a city.
+func matches(location, code string) bool {
+	if strings.EqualFold(location, code) {
+		return true
+	}
+	a, ok := airport.Lookup(code)
+	return ok && strings.EqualFold(a.City, location)
+}
+
+var _ Rebooker = (*Cached)(nil)
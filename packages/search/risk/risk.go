@@ -0,0 +1,163 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"github.com/tobyrushton/flyvia/packages/search/via"
+)
+
+// time to get from landing to the next ticket's gate at a self-transfer,
+// through arrivals and security again, and with time to collect bags and
+// check them in again.
+const (
+	defaultMinConnection         = 45 * time.Minute
+	defaultMinConnectionWithBags = 90 * time.Minute
+)
+
+var ErrNoReplacement = errors.New("no replacement ticket")
+
+// walk-up fares for a replacement ticket cost more than the one booked ahead.
+const walkUpMarkup = 1.5
+
+// Rebooker prices a replacement for a missed ticket, from and to airports,
+// leaving after the given time. ErrNoReplacement falls back to a walk-up
+// fare.
+type Rebooker interface {
+	Rebook(ctx context.Context, from, to string, after time.Time) (money.Money, error)
+}
+
+type Model struct {
+	// defaults to Heuristic.
+	Delays DelaySource
+	// when nil the missed ticket is assumed to cost a walk-up fare to replace.
+	Rebooker Rebooker
+
+	MinConnection         time.Duration
+	MinConnectionWithBags time.Duration
+}
+
+func NewModel(delays DelaySource, rebooker Rebooker) *Model {
+	return &Model{
+		Delays:                delays,
+		Rebooker:              rebooker,
+		MinConnection:         defaultMinConnection,
+		MinConnectionWithBags: defaultMinConnectionWithBags,
+	}
+}
+
+// connection is a change between tickets, arriving on the last flight of one
+// and needing to make the first flight of the next.
+type connection struct {
+	arriving  leg.Leg
+	departing leg.Leg
+	// what the ticket that would be lost cost.
+	onward money.Money
+}
+
+// Assess returns r with the chance of missing any connection between its
+// tickets and its price once the expected cost of rebooking is added.
+func (m *Model) Assess(ctx context.Context, r search.Result) (search.Result, error) {
+	delays := m.Delays
+	if delays == nil {
+		delays = Heuristic{}
+	}
+
+	minConnection := m.MinConnection
+	if r.RecollectBags {
+		minConnection = m.MinConnectionWithBags
+	}
+
+	total, err := r.Total()
+	if err != nil {
+		return search.Result{}, err
+	}
+
+	made := 1.0
+	expected := total
+	for _, c := range connections(r) {
+		p := m.probMiss(delays, c, minConnection)
+		made *= 1 - p
+		if p == 0 {
+			continue
+		}
+
+		cost, err := m.rebook(ctx, c)
+		if err != nil {
+			return search.Result{}, err
+		}
+		if expected, err = expected.Add(cost.Mul(p)); err != nil {
+			return search.Result{}, err
+		}
+	}
+
+	r.MissProbability = 1 - made
+	r.RiskAdjustedPrice = expected
+
+	return r, nil
+}
+
+func (m *Model) probMiss(delays DelaySource, c connection, minConnection time.Duration) float64 {
+	if len(c.arriving.Flights) == 0 {
+		return 0
+	}
+
+	slack := c.departing.DepartureTime.Sub(c.arriving.ArrivalTime) - minConnection
+	if slack <= 0 {
+		return 1
+	}
+
+	last := c.arriving.Flights[len(c.arriving.Flights)-1]
+	if p, ok := delays.ProbLate(last, slack); ok {
+		return p
+	}
+	p, _ := Heuristic{}.ProbLate(last, slack)
+	return p
+}
+
+func (m *Model) rebook(ctx context.Context, c connection) (money.Money, error) {
+	if m.Rebooker == nil {
+		return c.onward.Mul(walkUpMarkup), nil
+	}
+
+	price, err := m.Rebooker.Rebook(ctx, c.departing.DepartureAirport, c.departing.ArrivalAirport, c.departing.DepartureTime)
+	if errors.Is(err, ErrNoReplacement) {
+		return c.onward.Mul(walkUpMarkup), nil
+	}
+	return price, err
+}
+
+// connections lists the changes between tickets. A pair of return tickets
+// connects at the hub both ways, one-way tickets connect one after another.
+func connections(r search.Result) []connection {
+	itins := r.Itineries
+	cs := make([]connection, 0, len(itins))
+
+	if len(itins) == 2 && hasFlights(itins[0].Inbound) && hasFlights(itins[1].Inbound) {
+		return append(cs,
+			connection{arriving: itins[0].Outbound, departing: itins[1].Outbound, onward: itins[1].Price},
+			// missing the way home only loses the first ticket's return, half
+			// of it is a fair guess at its worth.
+			connection{arriving: itins[1].Inbound, departing: itins[0].Inbound, onward: itins[0].Price.Mul(0.5)},
+		)
+	}
+
+	for i := 1; i < len(itins); i++ {
+		cs = append(cs, connection{
+			arriving:  itins[i-1].Outbound,
+			departing: itins[i].Outbound,
+			onward:    itins[i].Price,
+		})
+	}
+	return cs
+}
+
+func hasFlights(l leg.Leg) bool {
+	return len(l.Flights) > 0
+}
+
+var _ via.Assessor = (*Model)(nil)
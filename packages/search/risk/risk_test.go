@@ -0,0 +1,177 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money/moneytest"
+	"github.com/tobyrushton/flyvia/packages/search/provider"
+	"github.com/tobyrushton/flyvia/packages/search/provider/providertest"
+	"github.com/tobyrushton/flyvia/packages/search/via"
+)
+
+var day = time.Date(2030, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// fixed is late a tenth of the time, whatever the layover.
+type fixed struct{}
+
+func (fixed) ProbLate(leg.Flight, time.Duration) (float64, bool) {
+	return 0.1, true
+}
+
+func ticket(from, to string, dep time.Time, price float64) itinery.Itinery {
+	f := leg.Flight{
+		DepartureAirport: from,
+		ArrivalAirport:   to,
+		DepartureTime:    dep,
+		ArrivalTime:      dep.Add(2 * time.Hour),
+	}
+	return itinery.Itinery{
+		Outbound: leg.Leg{
+			Flights:          []leg.Flight{f},
+			DepartureAirport: from,
+			ArrivalAirport:   to,
+			DepartureTime:    f.DepartureTime,
+			ArrivalTime:      f.ArrivalTime,
+		},
+		Price: moneytest.GBP(price),
+	}
+}
+
+func TestHeuristic(t *testing.T) {
+	morning := leg.Flight{DepartureTime: day.Add(7 * time.Hour), AirlineCode: "BA"}
+	evening := leg.Flight{DepartureTime: day.Add(19 * time.Hour), AirlineCode: "FR"}
+
+	short, _ := Heuristic{}.ProbLate(morning, 15*time.Minute)
+	long, _ := Heuristic{}.ProbLate(morning, 3*time.Hour)
+	if long >= short {
+		t.Errorf("expected a longer layover to be safer, got %.3f for 3h and %.3f for 15m", long, short)
+	}
+	if long < probCancelled {
+		t.Errorf("expected cancellations to be a floor, got %.3f", long)
+	}
+
+	late, _ := Heuristic{}.ProbLate(evening, 15*time.Minute)
+	if late <= short {
+		t.Errorf("expected an evening low-cost flight to be riskier, got %.3f and %.3f", late, short)
+	}
+}
+
+func TestAssess(t *testing.T) {
+	r, err := search.NewChain(
+		ticket("STN", "DUB", day.Add(8*time.Hour), 40),
+		ticket("DUB", "JFK", day.Add(13*time.Hour), 300),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewModel(fixed{}, nil)
+	assessed, err := m.Assess(context.Background(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if math.Abs(assessed.MissProbability-0.1) > 1e-9 {
+		t.Errorf("expected a 10%% chance of missing the connection, got %v", assessed.MissProbability)
+	}
+	// 340 plus a tenth of a 450 walk-up fare.
+	if assessed.RiskAdjustedPrice != moneytest.GBP(385) {
+		t.Errorf("expected a risk-adjusted price of 385, got %s", assessed.RiskAdjustedPrice)
+	}
+
+	// landing at 10:00 with bags to recollect leaves no time for a 11:00 flight.
+	r, _ = search.NewChain(
+		ticket("STN", "DUB", day.Add(8*time.Hour), 40),
+		ticket("DUB", "JFK", day.Add(11*time.Hour), 300),
+	)
+	r.RecollectBags = true
+	assessed, err = m.Assess(context.Background(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assessed.MissProbability != 1 {
+		t.Errorf("expected the connection to be missed, got %v", assessed.MissProbability)
+	}
+}
+
+// twoDays has a cheap morning flight and a dearer evening one for the first
+// two days.
+func twoDays(
+	_ context.Context,
+	req provider.Request,
+) ([]itinery.Itinery, error) {
+	if req.DepartureDate.After(day.AddDate(0, 0, 1)) {
+		return []itinery.Itinery{}, nil
+	}
+	return []itinery.Itinery{
+		ticket(req.Origin, req.Destination, req.DepartureDate.Add(9*time.Hour), 250),
+		ticket(req.Origin, req.Destination, req.DepartureDate.Add(18*time.Hour), 420),
+	}, nil
+}
+
+func TestCached_Rebook(t *testing.T) {
+	p := &providertest.Provider{SearchFunc: twoDays}
+	s := via.New(p, time.Hour, 6*time.Hour)
+	req := provider.Request{Origin: "London", Destination: "New York", DepartureDate: day, Party: provider.Party{Adults: 1}}
+
+	// the hub to destination ticket as via would have searched it.
+	if _, err := s.Ticket(context.Background(), provider.Request{Origin: "DUB", Destination: "New York", DepartureDate: day, Party: provider.Party{Adults: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewCached(s, req)
+
+	price, err := c.Rebook(context.Background(), "DUB", "JFK", day.Add(13*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price != moneytest.GBP(420) {
+		t.Errorf("expected the evening flight at 420, got %s", price)
+	}
+	if p.Searches() != 1 {
+		t.Errorf("expected the cached ticket to be used, got %d searches", p.Searches())
+	}
+
+	price, err = c.Rebook(context.Background(), "DUB", "JFK", day.Add(20*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price != moneytest.GBP(250) {
+		t.Errorf("expected the next morning's flight at 250, got %s", price)
+	}
+
+	if _, err := c.Rebook(context.Background(), "DUB", "JFK", day.Add(44*time.Hour)); !errors.Is(err, ErrNoReplacement) {
+		t.Errorf("expected ErrNoReplacement, got %v", err)
+	}
+}
+
+func TestSearcherAssesses(t *testing.T) {
+	s := via.New(&providertest.Provider{SearchFunc: twoDays}, time.Hour, 8*time.Hour)
+	s.Risk = NewModel(fixed{}, nil)
+
+	results, err := s.Search(context.Background(), provider.Request{
+		Origin:        "London",
+		Destination:   "New York",
+		DepartureDate: day,
+		Party:         provider.Party{Adults: 1},
+	}, "DUB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 {
+		t.Fatal("expected a connection through DUB")
+	}
+
+	for _, r := range results {
+		if r.MissProbability <= 0 || r.RiskAdjustedPrice.IsZero() {
+			t.Errorf("expected every result to be assessed, got %.3f and %s", r.MissProbability, r.RiskAdjustedPrice)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package rtw
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/airport"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"github.com/tobyrushton/flyvia/packages/search/provider"
+	"golang.org/x/text/currency"
+)
+
+const defaultMinStay = 3 // days
+
+type Direction int64
+
+const (
+	Eastbound Direction = iota + 1
+	Westbound
+)
+
+// the regions a round-the-world trip usually passes through, stops are
+// suggested from these when none are given.
+var mainline = []airport.Region{
+	airport.Europe,
+	airport.Asia,
+	airport.Oceania,
+	airport.NorthAmerica,
+}
+
+type Stop struct {
+	// airport code or city to stop at, when empty the cheapest airport in
+	// Region is suggested once the journey gets there.
+	Location string
+	Region   airport.Region
+	// minimum number of days to stay, zero for the default.
+	MinStay int
+}
+
+func (s Stop) name() string {
+	if s.Location != "" {
+		return s.Location
+	}
+	return string(s.Region)
+}
+
+type Request struct {
+	// airport code or city the journey starts and ends at.
+	Origin    string
+	Direction Direction
+	Start     time.Time
+	// in any order, they are visited in the order they fall going round.
+	Stops []Stop
+
+	provider.Party
+
+	Currency currency.Unit
+	Class    provider.Class
+}
+
+func (r Request) Validate() error {
+	if r.Direction != Eastbound && r.Direction != Westbound {
+		return fmt.Errorf("direction must be eastbound or westbound")
+	}
+	if r.Start.IsZero() {
+		return fmt.Errorf("start date is required")
+	}
+	for _, s := range r.Stops {
+		if s.MinStay < 0 {
+			return fmt.Errorf("stop %s: minimum stay cannot be negative", s.name())
+		}
+	}
+	return nil
+}
+
+type Ticket struct {
+	From      string
+	To        string
+	Departure time.Time
+	Itinery   itinery.Itinery
+}
+
+type Journey struct {
+	Direction Direction
+	Tickets   []Ticket
+	// the tickets chained together, Result.Price is the journey total.
+	Result search.Result
+}
+
+type positioned struct {
+	Stop
+	lon float64
+}
+
+// Build prices a round-the-world journey as separate one-way tickets, going
+// round in the requested direction and staying at least MinStay at each stop.
+func Build(
+	ctx context.Context,
+	p provider.Provider,
+	req Request,
+) (*Journey, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	origin, err := resolve(req.Origin)
+	if err != nil {
+		return nil, err
+	}
+
+	stops := req.Stops
+	if len(stops) == 0 {
+		stops = Suggest(origin.Region)
+	}
+
+	ordered, err := order(origin.Lon, req.Direction, stops)
+	if err != nil {
+		return nil, err
+	}
+
+	// the origin and city stops are searched as given so a city covers all of
+	// its airports.
+	j := &Journey{Direction: req.Direction}
+	visited := []string{req.Origin}
+	from := req.Origin
+	date := req.Start
+
+	for _, s := range ordered {
+		to := s.Location
+		if to == "" {
+			to, err = suggest(ctx, p, req, from, date, s.Region, visited)
+			if err != nil {
+				return nil, err
+			}
+		} else if a, ok := airport.Lookup(to); ok {
+			to = a.Code
+		}
+
+		t, err := ticket(ctx, p, req, from, to, date)
+		if err != nil {
+			return nil, err
+		}
+		j.Tickets = append(j.Tickets, t)
+
+		minStay := s.MinStay
+		if minStay == 0 {
+			minStay = defaultMinStay
+		}
+
+		arrival := t.Itinery.Outbound.ArrivalTime
+		date = time.Date(arrival.Year(), arrival.Month(), arrival.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, minStay)
+		visited = append(visited, to)
+		from = to
+	}
+
+	t, err := ticket(ctx, p, req, from, req.Origin, date)
+	if err != nil {
+		return nil, err
+	}
+	j.Tickets = append(j.Tickets, t)
+
+	itins := make([]itinery.Itinery, len(j.Tickets))
+	for i, t := range j.Tickets {
+		itins[i] = t.Itinery
+	}
+	if j.Result, err = search.NewChain(itins...); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// Suggest returns stops in the mainline regions other than the one the
+// journey starts in, with the airports chosen along the way.
+func Suggest(origin airport.Region) []Stop {
+	stops := make([]Stop, 0, len(mainline))
+	for _, r := range mainline {
+		if r != origin {
+			stops = append(stops, Stop{Region: r, MinStay: defaultMinStay})
+		}
+	}
+	return stops
+}
+
+// order sorts stops by how far round they are from the origin going in the
+// given direction.
+func order(originLon float64, d Direction, stops []Stop) ([]positioned, error) {
+	ps := make([]positioned, len(stops))
+	for i, s := range stops {
+		lon, err := longitude(s)
+		if err != nil {
+			return nil, err
+		}
+		ps[i] = positioned{Stop: s, lon: lon}
+	}
+
+	sort.SliceStable(ps, func(i, j int) bool {
+		return around(originLon, ps[i].lon, d) < around(originLon, ps[j].lon, d)
+	})
+
+	return ps, nil
+}
+
+// around is how many degrees of longitude there are between from and to going
+// in the given direction.
+func around(from, to float64, d Direction) float64 {
+	delta := to - from
+	if d == Westbound {
+		delta = -delta
+	}
+	return math.Mod(delta+360, 360)
+}
+
+func longitude(s Stop) (float64, error) {
+	if s.Location != "" {
+		a, err := resolve(s.Location)
+		if err != nil {
+			return 0, err
+		}
+		return a.Lon, nil
+	}
+
+	// the average longitude of a region, going via vectors so regions
+	// spanning the antimeridian don't average out to the wrong side.
+	var x, y float64
+	n := 0
+	for _, a := range airport.All() {
+		if a.Region != s.Region {
+			continue
+		}
+		x += math.Cos(a.Lon * math.Pi / 180)
+		y += math.Sin(a.Lon * math.Pi / 180)
+		n++
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("no airports known in region %q", s.Region)
+	}
+
+	return math.Atan2(y, x) * 180 / math.Pi, nil
+}
+
+// resolve finds an airport to place the location on the map, any of a city's
+// airports will do.
+func resolve(location string) (airport.Airport, error) {
+	if a, ok := airport.Lookup(location); ok {
+		return a, nil
+	}
+	if in := airport.InCity(location); len(in) > 0 {
+		return in[0], nil
+	}
+	return airport.Airport{}, fmt.Errorf("unknown location %q", location)
+}
+
+// suggest picks the cheapest airport in the region to fly to next.
+func suggest(
+	ctx context.Context,
+	p provider.Provider,
+	req Request,
+	from string,
+	date time.Time,
+	region airport.Region,
+	visited []string,
+) (string, error) {
+	explore := provider.ExploreRequest{
+		Origins:       []string{from},
+		DepartureFrom: date,
+		Regions:       []airport.Region{region},
+		Party:         req.Party,
+		Currency:      req.Currency,
+		Class:         req.Class,
+	}
+
+	eis, err := p.Explore(ctx, explore)
+	if err != nil {
+		return "", err
+	}
+
+	best := ""
+	var price money.Money
+	for _, ei := range eis {
+		if !explore.Matches(ei) || slices.ContainsFunc(visited, ei.Reaches) {
+			continue
+		}
+		if best != "" {
+			if c, err := ei.Price.Cmp(price); err != nil || c >= 0 {
+				continue
+			}
+		}
+		best, price = ei.Destination, ei.Price
+	}
+
+	if best == "" {
+		return "", fmt.Errorf("no destinations in %s reachable from %s", region, from)
+	}
+	return best, nil
+}
+
+func ticket(
+	ctx context.Context,
+	p provider.Provider,
+	req Request,
+	from, to string,
+	date time.Time,
+) (Ticket, error) {
+	itins, err := p.Search(ctx, provider.Request{
+		Origin:        from,
+		Destination:   to,
+		DepartureDate: date,
+		Party:         req.Party,
+		Currency:      req.Currency,
+		Class:         req.Class,
+	})
+	if err != nil {
+		return Ticket{}, err
+	}
+	cheapest, ok := itinery.Cheapest(itins)
+	if !ok {
+		return Ticket{}, fmt.Errorf("no flights from %s to %s on %s", from, to, date.Format(time.DateOnly))
+	}
+
+	return Ticket{
+		From:      from,
+		To:        to,
+		Departure: cheapest.Outbound.DepartureTime,
+		Itinery:   cheapest,
+	}, nil
+}
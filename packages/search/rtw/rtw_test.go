@@ -0,0 +1,190 @@
+package rtw
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/airport"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money/moneytest"
+	"github.com/tobyrushton/flyvia/packages/search/provider"
+	"github.com/tobyrushton/flyvia/packages/search/provider/providertest"
+)
+
+var start = time.Date(2030, 9, 1, 0, 0, 0, 0, time.UTC)
+
+type fakeTrips struct {
+	searched []string
+}
+
+func (f *fakeTrips) explore(
+	_ context.Context,
+	req provider.ExploreRequest,
+) ([]itinery.ExploreItinery, error) {
+	return []itinery.ExploreItinery{
+		{Destination: "SIN", Price: moneytest.GBP(400)},
+		{Destination: "BKK", Price: moneytest.GBP(350)},
+		{Destination: "CDG", Price: moneytest.GBP(50)},
+	}, nil
+}
+
+// every flight leaves at 10:00 and lands at 20:00 the same day.
+func (f *fakeTrips) search(
+	_ context.Context,
+	req provider.Request,
+) ([]itinery.Itinery, error) {
+	f.searched = append(f.searched, req.Origin+"-"+req.Destination+" "+req.DepartureDate.Format(time.DateOnly))
+
+	return []itinery.Itinery{{
+		Outbound: leg.Leg{
+			DepartureAirport: req.Origin,
+			ArrivalAirport:   req.Destination,
+			DepartureTime:    req.DepartureDate.Add(10 * time.Hour),
+			ArrivalTime:      req.DepartureDate.Add(20 * time.Hour),
+		},
+		Price: moneytest.GBP(500),
+	}}, nil
+}
+
+func (f *fakeTrips) provider() *providertest.Provider {
+	return &providertest.Provider{ExploreFunc: f.explore, SearchFunc: f.search}
+}
+
+func TestBuild_Eastbound(t *testing.T) {
+	p := &fakeTrips{}
+
+	j, err := Build(context.Background(), p.provider(), Request{
+		Origin:    "London",
+		Direction: Eastbound,
+		Start:     start,
+		Stops: []Stop{
+			{Location: "JFK", MinStay: 2},
+			{Region: airport.Asia, MinStay: 4},
+			{Location: "SYD", MinStay: 5},
+		},
+		Party: provider.Party{Adults: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"London-BKK 2030-09-01",
+		"BKK-SYD 2030-09-05",
+		"SYD-JFK 2030-09-10",
+		"JFK-London 2030-09-12",
+	}
+	if len(p.searched) != len(want) {
+		t.Fatalf("expected %d tickets, got %v", len(want), p.searched)
+	}
+	for i := range want {
+		if p.searched[i] != want[i] {
+			t.Errorf("ticket %d: expected %s, got %s", i, want[i], p.searched[i])
+		}
+	}
+
+	if j.Result.Price != moneytest.GBP(2000) {
+		t.Errorf("expected total of 2000, got %s", j.Result.Price)
+	}
+	if len(j.Tickets) != 4 || j.Tickets[1].Departure.Day() != 5 {
+		t.Error("expected every ticket with its departure date")
+	}
+}
+
+func TestBuild_Westbound(t *testing.T) {
+	p := &fakeTrips{}
+
+	_, err := Build(context.Background(), p.provider(), Request{
+		Origin:    "LHR",
+		Direction: Westbound,
+		Start:     start,
+		Stops: []Stop{
+			{Location: "SYD"},
+			{Location: "JFK"},
+			{Location: "SIN"},
+		},
+		Party: provider.Party{Adults: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.searched[0] != "LHR-JFK 2030-09-01" || p.searched[1][:7] != "JFK-SYD" || p.searched[2][:7] != "SYD-SIN" {
+		t.Errorf("expected LHR-JFK-SYD-SIN going west, got %v", p.searched)
+	}
+}
+
+func TestBuild_SuggestsRegions(t *testing.T) {
+	stops := Suggest(airport.Europe)
+	if len(stops) != 3 {
+		t.Fatalf("expected 3 suggested regions, got %d", len(stops))
+	}
+	for _, s := range stops {
+		if s.Region == airport.Europe {
+			t.Error("expected the origin region to not be suggested")
+		}
+	}
+}
+
+func TestBuild_UnknownLocation(t *testing.T) {
+	_, err := Build(context.Background(), (&fakeTrips{}).provider(), Request{
+		Origin:    "Atlantis",
+		Direction: Eastbound,
+		Start:     start,
+	})
+	if err == nil {
+		t.Error("expected an error for an unknown origin")
+	}
+}
+
+func TestBuild_NeedsDirection(t *testing.T) {
+	p := &fakeTrips{}
+	_, err := Build(context.Background(), p.provider(), Request{
+		Origin: "London",
+		Start:  start,
+	})
+	if err == nil {
+		t.Error("expected an error for a journey without a direction")
+	}
+	if len(p.searched) != 0 {
+		t.Errorf("expected nothing to be searched, got %v", p.searched)
+	}
+}
+
+func TestBuild_CityStop(t *testing.T) {
+	p := &fakeTrips{}
+
+	_, err := Build(context.Background(), p.provider(), Request{
+		Origin:    "LHR",
+		Direction: Westbound,
+		Start:     start,
+		Stops:     []Stop{{Location: "New York"}},
+		Party:     provider.Party{Adults: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.searched[0] != "LHR-New York 2030-09-01" {
+		t.Errorf("expected the stop to be searched as the city, got %v", p.searched)
+	}
+}
+
+func TestBuild_NegativeMinStay(t *testing.T) {
+	p := &fakeTrips{}
+	_, err := Build(context.Background(), p.provider(), Request{
+		Origin:    "LHR",
+		Direction: Eastbound,
+		Start:     start,
+		Stops:     []Stop{{Location: "JFK", MinStay: -1}},
+		Party:     provider.Party{Adults: 1},
+	})
+	if err == nil {
+		t.Error("expected an error for a negative minimum stay")
+	}
+	if len(p.searched) != 0 {
+		t.Errorf("expected nothing to be searched, got %v", p.searched)
+	}
+}
@@ -0,0 +1,373 @@
+package tour
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"github.com/tobyrushton/flyvia/packages/search/provider"
+	"golang.org/x/text/currency"
+)
+
+const (
+	defaultBeamWidth = 5
+	defaultConfirm   = 3
+)
+
+var (
+	ErrNoTour = errors.New("no tour fits the budget")
+	// returned for MinCost tours with more stops than days.
+	ErrTooShort = errors.New("tour too short for its stops")
+)
+
+type Objective int64
+
+const (
+	// visit as many destinations as the budget allows, up to Request.Stops.
+	MaxDestinations Objective = iota + 1
+	// visit exactly Request.Stops destinations as cheaply as possible.
+	MinCost
+)
+
+type Request struct {
+	Origin string
+	Budget money.Money
+
+	// leave on Start and be home by End, the time in between is split evenly
+	// across the destinations.
+	Start time.Time
+	End   time.Time
+	Stops int
+
+	Objective Objective
+
+	provider.Party
+
+	Currency currency.Unit
+	Class    provider.Class
+}
+
+type Tour struct {
+	// in the order they are visited.
+	Destinations []string
+	// what explore prices suggested the tour would cost.
+	Estimate money.Money
+	// the confirmed one-way tickets, ending back at the origin.
+	Result search.Result
+}
+
+// Planner plans tours using explore prices to choose destinations and their
+// order, then confirms the best few with searches.
+type Planner struct {
+	p provider.Provider
+
+	// number of partial tours kept at each step.
+	BeamWidth int
+	// number of planned tours confirmed with searches.
+	Confirm int
+
+	mu       sync.Mutex
+	explores map[string][]itinery.ExploreItinery
+	searches map[string][]itinery.Itinery
+}
+
+func NewPlanner(p provider.Provider) *Planner {
+	return &Planner{
+		p:         p,
+		BeamWidth: defaultBeamWidth,
+		Confirm:   defaultConfirm,
+		explores:  make(map[string][]itinery.ExploreItinery),
+		searches:  make(map[string][]itinery.Itinery),
+	}
+}
+
+type plan struct {
+	stops    []string
+	estimate money.Money
+}
+
+func (p plan) at(origin string) string {
+	if len(p.stops) == 0 {
+		return origin
+	}
+	return p.stops[len(p.stops)-1]
+}
+
+func (pl *Planner) Plan(ctx context.Context, req Request) (*Tour, error) {
+	if req.Stops < 1 {
+		return nil, fmt.Errorf("a tour needs at least one stop")
+	}
+	if !req.End.After(req.Start) {
+		return nil, fmt.Errorf("tour must end after it starts")
+	}
+	if req.Budget.Amount <= 0 {
+		return nil, fmt.Errorf("a tour needs a budget")
+	}
+	if req.Budget.Currency != req.Currency {
+		return nil, fmt.Errorf("%w: budget is in %s, tour is priced in %s", money.ErrCurrencyMismatch, req.Budget.Currency, req.Currency)
+	}
+
+	if req.Objective == MinCost {
+		return pl.planStops(ctx, req, req.Stops)
+	}
+
+	for stops := req.Stops; stops > 0; stops-- {
+		t, err := pl.planStops(ctx, req, stops)
+		// fewer stops may fit in the time, or the budget.
+		if errors.Is(err, ErrNoTour) || errors.Is(err, ErrTooShort) {
+			continue
+		}
+		return t, err
+	}
+
+	return nil, ErrNoTour
+}
+
+// planStops beam searches tours with exactly the given number of stops on
+// explore prices, then confirms the most promising and returns the cheapest.
+func (pl *Planner) planStops(ctx context.Context, req Request, stops int) (*Tour, error) {
+	dates, err := departures(req.Start, req.End, stops)
+	if err != nil {
+		return nil, err
+	}
+
+	beam := []plan{{}}
+	for i := 0; i < stops; i++ {
+		next := make([]plan, 0)
+
+		for _, p := range beam {
+			eis, err := pl.explore(ctx, req, p.at(req.Origin), dates[i])
+			if err != nil {
+				return nil, err
+			}
+
+			for _, ei := range eis {
+				if slices.Contains(p.stops, ei.Destination) || ei.Reaches(req.Origin) {
+					continue
+				}
+				estimate, err := p.estimate.Add(ei.Price)
+				if err != nil || overBudget(req, estimate) {
+					continue
+				}
+				next = append(next, plan{
+					stops:    append(slices.Clone(p.stops), ei.Destination),
+					estimate: estimate,
+				})
+			}
+		}
+
+		if beam, err = best(next, pl.BeamWidth); err != nil {
+			return nil, err
+		}
+	}
+
+	// price the flight home, which explore may not have given us.
+	complete := make([]plan, 0, len(beam))
+	for _, p := range beam {
+		price, err := pl.home(ctx, req, p.at(req.Origin), dates[stops])
+		if err != nil {
+			return nil, err
+		}
+		if price.IsZero() {
+			continue
+		}
+		if p.estimate, err = p.estimate.Add(price); err != nil {
+			continue
+		}
+		complete = append(complete, p)
+	}
+	beam, err = best(complete, len(complete))
+	if err != nil {
+		return nil, err
+	}
+
+	var cheapest *Tour
+	for i := 0; i < pl.Confirm && i < len(beam); i++ {
+		t, err := pl.confirm(ctx, req, beam[i], dates)
+		if err != nil {
+			return nil, err
+		}
+		if t == nil || overBudget(req, t.Result.Price) {
+			continue
+		}
+		if cheapest != nil {
+			if c, err := t.Result.Price.Cmp(cheapest.Result.Price); err != nil || c >= 0 {
+				continue
+			}
+		}
+		cheapest = t
+	}
+
+	if cheapest == nil {
+		return nil, ErrNoTour
+	}
+
+	return cheapest, nil
+}
+
+// confirm searches every ticket of the plan, taking the cheapest of each. A
+// nil tour means one of the tickets couldn't be found.
+func (pl *Planner) confirm(
+	ctx context.Context,
+	req Request,
+	p plan,
+	dates []time.Time,
+) (*Tour, error) {
+	route := append([]string{req.Origin}, p.stops...)
+	route = append(route, req.Origin)
+
+	tickets := make([]itinery.Itinery, 0, len(route)-1)
+	for i := 0; i < len(route)-1; i++ {
+		itins, err := pl.search(ctx, req, route[i], route[i+1], dates[i])
+		if err != nil {
+			return nil, err
+		}
+		cheapest, ok := itinery.Cheapest(itins)
+		if !ok {
+			return nil, nil
+		}
+		tickets = append(tickets, cheapest)
+	}
+
+	r, err := search.NewChain(tickets...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tour{
+		Destinations: p.stops,
+		Estimate:     p.estimate,
+		Result:       r,
+	}, nil
+}
+
+func (pl *Planner) home(ctx context.Context, req Request, from string, date time.Time) (money.Money, error) {
+	eis, err := pl.explore(ctx, req, from, date)
+	if err != nil {
+		return money.Money{}, err
+	}
+	for _, ei := range eis {
+		if ei.Reaches(req.Origin) {
+			return ei.Price, nil
+		}
+	}
+
+	itins, err := pl.search(ctx, req, from, req.Origin, date)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	cheapest, _ := itinery.Cheapest(itins)
+	return cheapest.Price, nil
+}
+
+func (pl *Planner) explore(
+	ctx context.Context,
+	req Request,
+	from string,
+	date time.Time,
+) ([]itinery.ExploreItinery, error) {
+	explore := provider.ExploreRequest{
+		Origins:       []string{from},
+		DepartureFrom: date,
+		MaxPrice:      req.Budget,
+		Party:         req.Party,
+		Currency:      req.Currency,
+		Class:         req.Class,
+	}
+	k := explore.Key()
+
+	pl.mu.Lock()
+	eis, ok := pl.explores[k]
+	pl.mu.Unlock()
+	if ok {
+		return eis, nil
+	}
+
+	eis, err := pl.p.Explore(ctx, explore)
+	if err != nil {
+		return nil, err
+	}
+
+	pl.mu.Lock()
+	pl.explores[k] = eis
+	pl.mu.Unlock()
+
+	return eis, nil
+}
+
+func (pl *Planner) search(
+	ctx context.Context,
+	req Request,
+	from, to string,
+	date time.Time,
+) ([]itinery.Itinery, error) {
+	search := provider.Request{
+		Origin:        from,
+		Destination:   to,
+		DepartureDate: date,
+		Party:         req.Party,
+		Currency:      req.Currency,
+		Class:         req.Class,
+	}
+	k := search.Key()
+
+	pl.mu.Lock()
+	itins, ok := pl.searches[k]
+	pl.mu.Unlock()
+	if ok {
+		return itins, nil
+	}
+
+	itins, err := pl.p.Search(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+
+	pl.mu.Lock()
+	pl.searches[k] = itins
+	pl.mu.Unlock()
+
+	return itins, nil
+}
+
+// departures splits the tour evenly across the stops, returning the day of
+// each flight including the one home. Every stop gets at least a day, so the
+// tour has to be at least as many days long as it has stops.
+func departures(start, end time.Time, stops int) ([]time.Time, error) {
+	days := provider.Days(start, end)
+	if days < stops {
+		return nil, fmt.Errorf("%w: %d days for %d stops", ErrTooShort, days, stops)
+	}
+	stay := days / stops
+
+	dates := make([]time.Time, stops+1)
+	for i := range dates {
+		dates[i] = start.AddDate(0, 0, i*stay)
+	}
+
+	return dates, nil
+}
+
+// overBudget reports whether price is more than the budget, a price in another
+// currency can't be shown to fit so is over.
+func overBudget(req Request, price money.Money) bool {
+	c, err := price.Cmp(req.Budget)
+	return err != nil || c > 0
+}
+
+func best(plans []plan, n int) ([]plan, error) {
+	if err := money.Sort(plans, func(p plan) money.Money { return p.estimate }); err != nil {
+		return nil, err
+	}
+	if len(plans) > n {
+		plans = plans[:n]
+	}
+	return plans, nil
+}
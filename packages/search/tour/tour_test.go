@@ -0,0 +1,260 @@
+package tour
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"github.com/tobyrushton/flyvia/packages/search/money/moneytest"
+	"github.com/tobyrushton/flyvia/packages/search/provider"
+	"github.com/tobyrushton/flyvia/packages/search/provider/providertest"
+	"golang.org/x/text/currency"
+)
+
+var start = time.Date(2030, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// one-way fares between airports in the currency asked for, explore and search
+// agree on prices.
+var fares = map[string]map[string]float64{
+	"London": {"BCN": 50, "ROM": 60, "ATH": 120},
+	"BCN":    {"ROM": 40, "ATH": 150, "LHR": 45},
+	"ROM":    {"BCN": 40, "ATH": 70, "LHR": 55},
+	"ATH":    {"BCN": 150, "ROM": 70, "LHR": 90},
+}
+
+func fakeProvider() *providertest.Provider {
+	return &providertest.Provider{ExploreFunc: exploreFares, SearchFunc: searchFares}
+}
+
+func exploreFares(
+	_ context.Context,
+	req provider.ExploreRequest,
+) ([]itinery.ExploreItinery, error) {
+	eis := make([]itinery.ExploreItinery, 0)
+	for dest, price := range fares[req.Origins[0]] {
+		ei := itinery.ExploreItinery{Destination: dest, Price: money.New(price, req.Currency)}
+		if dest == "LHR" {
+			ei.City = "London"
+		}
+		eis = append(eis, ei)
+	}
+	return eis, nil
+}
+
+func searchFares(
+	_ context.Context,
+	req provider.Request,
+) ([]itinery.Itinery, error) {
+	dest := req.Destination
+	if dest == "London" {
+		dest = "LHR"
+	}
+
+	price, ok := fares[req.Origin][dest]
+	if !ok {
+		return []itinery.Itinery{}, nil
+	}
+
+	return []itinery.Itinery{{
+		Outbound: leg.Leg{
+			DepartureAirport: req.Origin,
+			ArrivalAirport:   dest,
+			DepartureTime:    req.DepartureDate.Add(9 * time.Hour),
+			ArrivalTime:      req.DepartureDate.Add(11 * time.Hour),
+		},
+		Price: money.New(price, req.Currency),
+	}}, nil
+}
+
+func TestPlan_MinCost(t *testing.T) {
+	tour, err := NewPlanner(fakeProvider()).Plan(context.Background(), Request{
+		Origin:    "London",
+		Budget:    moneytest.GBP(1000),
+		Start:     start,
+		End:       start.AddDate(0, 0, 9),
+		Stops:     3,
+		Objective: MinCost,
+		Party:     provider.Party{Adults: 1},
+		Currency:  currency.GBP,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// London-BCN-ROM-ATH-London is 50+40+70+90.
+	if tour.Result.Price != moneytest.GBP(250) {
+		t.Errorf("expected cheapest tour to cost 250, got %s (%v)", tour.Result.Price, tour.Destinations)
+	}
+	if len(tour.Result.Itineries) != 4 {
+		t.Fatalf("expected 4 tickets, got %d", len(tour.Result.Itineries))
+	}
+
+	// 9 days over 3 stops is 3 days at each.
+	if tour.Result.StopLengths[0] != 3*24*time.Hour-2*time.Hour {
+		t.Errorf("expected 3 days at the first stop, got %v", tour.Result.StopLengths[0])
+	}
+}
+
+func TestPlan_MaxDestinationsDropsStopsOverBudget(t *testing.T) {
+	tour, err := NewPlanner(fakeProvider()).Plan(context.Background(), Request{
+		Origin:    "London",
+		Budget:    moneytest.GBP(120),
+		Start:     start,
+		End:       start.AddDate(0, 0, 9),
+		Stops:     3,
+		Objective: MaxDestinations,
+		Party:     provider.Party{Adults: 1},
+		Currency:  currency.GBP,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// three stops can't fit in 120, London-BCN-ROM-London is 50+40+55.
+	if len(tour.Destinations) != 1 {
+		t.Errorf("expected 1 destination within budget, got %v", tour.Destinations)
+	}
+	if c, err := tour.Result.Price.Cmp(moneytest.GBP(120)); err != nil || c > 0 {
+		t.Errorf("expected tour within budget, got %s", tour.Result.Price)
+	}
+}
+
+func TestPlan_NothingFits(t *testing.T) {
+	_, err := NewPlanner(fakeProvider()).Plan(context.Background(), Request{
+		Origin:    "London",
+		Budget:    moneytest.GBP(50),
+		Start:     start,
+		End:       start.AddDate(0, 0, 9),
+		Stops:     2,
+		Objective: MaxDestinations,
+		Party:     provider.Party{Adults: 1},
+		Currency:  currency.GBP,
+	})
+	if err != ErrNoTour {
+		t.Errorf("expected ErrNoTour, got %v", err)
+	}
+}
+
+func TestPlan_TooShortForStops(t *testing.T) {
+	req := Request{
+		Origin:    "London",
+		Budget:    moneytest.GBP(1000),
+		Start:     start,
+		End:       start.AddDate(0, 0, 2),
+		Stops:     3,
+		Objective: MinCost,
+		Party:     provider.Party{Adults: 1},
+		Currency:  currency.GBP,
+	}
+
+	if _, err := NewPlanner(fakeProvider()).Plan(context.Background(), req); !errors.Is(err, ErrTooShort) {
+		t.Errorf("expected ErrTooShort for 3 stops in 2 days, got %v", err)
+	}
+
+	req.Objective = MaxDestinations
+	tour, err := NewPlanner(fakeProvider()).Plan(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tour.Destinations) != 2 {
+		t.Errorf("expected 2 stops to fit in 2 days, got %v", tour.Destinations)
+	}
+	last := tour.Result.Itineries[len(tour.Result.Itineries)-1]
+	if day := last.Outbound.DepartureTime.Truncate(24 * time.Hour); day.After(req.End) {
+		t.Errorf("expected the flight home by %v, got %v", req.End, day)
+	}
+}
+
+// recorder records what fakeProvider is asked to explore for.
+type recorder struct {
+	currencies []currency.Unit
+	parties    []provider.Party
+}
+
+func (r *recorder) provider() *providertest.Provider {
+	return &providertest.Provider{
+		ExploreFunc: func(ctx context.Context, req provider.ExploreRequest) ([]itinery.ExploreItinery, error) {
+			r.currencies = append(r.currencies, req.Currency)
+			r.parties = append(r.parties, req.Party)
+			return exploreFares(ctx, req)
+		},
+		SearchFunc: searchFares,
+	}
+}
+
+func TestPlan_CacheKeyedByRequest(t *testing.T) {
+	p := &recorder{}
+	pl := NewPlanner(p.provider())
+
+	req := Request{
+		Origin:    "London",
+		Budget:    moneytest.GBP(1000),
+		Start:     start,
+		End:       start.AddDate(0, 0, 9),
+		Stops:     1,
+		Objective: MinCost,
+		Party:     provider.Party{Adults: 1},
+		Currency:  currency.GBP,
+	}
+	if _, err := pl.Plan(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	n := len(p.currencies)
+
+	req.Currency, req.Budget = currency.EUR, money.New(1000, currency.EUR)
+	if _, err := pl.Plan(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.currencies) == n || p.currencies[n] != currency.EUR {
+		t.Errorf("expected a new explore in EUR, got %v", p.currencies)
+	}
+}
+
+func TestPlan_KeepsParty(t *testing.T) {
+	p := &recorder{}
+	party := provider.Party{Adults: 1, Seniors: 1, InfantsOnLap: 1, Youths: 1}
+
+	if _, err := NewPlanner(p.provider()).Plan(context.Background(), Request{
+		Origin:    "London",
+		Budget:    moneytest.GBP(1000),
+		Start:     start,
+		End:       start.AddDate(0, 0, 9),
+		Stops:     1,
+		Objective: MinCost,
+		Party:     party,
+		Currency:  currency.GBP,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, got := range p.parties {
+		if got.Seniors != 1 || got.InfantsOnLap != 1 || got.Youths != 1 {
+			t.Fatalf("expected the whole party to be explored for, got %+v", got)
+		}
+	}
+}
+
+func TestPlan_Budget(t *testing.T) {
+	req := Request{
+		Origin:    "London",
+		Start:     start,
+		End:       start.AddDate(0, 0, 9),
+		Stops:     1,
+		Objective: MinCost,
+		Party:     provider.Party{Adults: 1},
+		Currency:  currency.GBP,
+	}
+
+	if _, err := NewPlanner(fakeProvider()).Plan(context.Background(), req); err == nil {
+		t.Error("expected an error for a tour without a budget")
+	}
+
+	req.Budget = money.New(1000, currency.EUR)
+	if _, err := NewPlanner(fakeProvider()).Plan(context.Background(), req); !errors.Is(err, money.ErrCurrencyMismatch) {
+		t.Errorf("expected a budget in another currency to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package via
+
+import (
+	"context"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"github.com/tobyrushton/flyvia/packages/search/provider"
+)
+
+// Upgrade is what a ticket of a result would cost in a premium cabin.
+type Upgrade struct {
+	// index into the result's itineries.
+	Ticket int
+	From   provider.Class
+	To     provider.Class
+	// false when the same flights aren't sold in the premium cabin, Price and
+	// Cost are then zero.
+	Available bool
+	Price     money.Money
+	// the extra over what the ticket costs now.
+	Cost money.Money
+}
+
+// Upgrades prices every ticket of r on the same flights in cabin, tickets
+// already in cabin or above are left out. req is the request r was found
+// with, for its passengers and currency.
+func (s *Searcher) Upgrades(
+	ctx context.Context,
+	req provider.Request,
+	r search.Result,
+	cabin provider.Class,
+) ([]Upgrade, error) {
+	upgrades := make([]Upgrade, 0, len(r.Itineries))
+
+	for i, itin := range r.Itineries {
+		current := itineryCabin(itin, req.Class)
+		if current >= cabin {
+			continue
+		}
+
+		ticketReq := ticketRequest(req, itin)
+		ticketReq.Class = cabin
+
+		premium, err := s.Ticket(ctx, ticketReq)
+		if err != nil {
+			return nil, err
+		}
+
+		u := Upgrade{Ticket: i, From: current, To: cabin}
+		for _, p := range premium {
+			if !sameFlights(itin, p) {
+				continue
+			}
+			cost, err := p.Price.Sub(itin.Price)
+			if err != nil {
+				return nil, err
+			}
+			if u.Available {
+				if c, err := p.Price.Cmp(u.Price); err != nil || c >= 0 {
+					continue
+				}
+			}
+			u.Available, u.Price, u.Cost = true, p.Price, cost
+		}
+		upgrades = append(upgrades, u)
+	}
+
+	return upgrades, nil
+}
+
+// ticketRequest is req narrowed to the route and dates of itin, in the cabin
+// it's flown in.
+func ticketRequest(req provider.Request, itin itinery.Itinery) provider.Request {
+	req.Origin = itin.Outbound.DepartureAirport
+	req.Destination = itin.Outbound.ArrivalAirport
+	req.DepartureDate = date(itin.Outbound.DepartureTime)
+	req.ReturnDate = time.Time{}
+	if len(itin.Inbound.Flights) > 0 {
+		req.ReturnDate = date(itin.Inbound.DepartureTime)
+	}
+	req.Class = itineryCabin(itin, req.Class)
+	return req
+}
+
+// itineryCabin is the lowest cabin flown on the itinery.
+func itineryCabin(itin itinery.Itinery, fallback provider.Class) provider.Class {
+	var lowest leg.Cabin
+	for _, l := range []leg.Leg{itin.Outbound, itin.Inbound} {
+		for _, f := range l.Flights {
+			if f.Cabin != 0 && (lowest == 0 || f.Cabin < lowest) {
+				lowest = f.Cabin
+			}
+		}
+	}
+	if lowest == 0 {
+		return fallback
+	}
+	return lowest
+}
+
+func sameFlights(a, b itinery.Itinery) bool {
+	return sameLeg(a.Outbound, b.Outbound) && sameLeg(a.Inbound, b.Inbound)
+}
+
+// sameLeg compares the physical flights, a codeshare can come back sold
+// under another flight number.
+func sameLeg(a, b leg.Leg) bool {
+	return len(a.Flights) == len(b.Flights) && a.ID() == b.ID()
+}
+
+// date is the local calendar day of t, which is what searches are made for.
+func date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,183 @@
+package via
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/baggage"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"github.com/tobyrushton/flyvia/packages/search/provider"
+)
+
+// Verify prices the tickets of the first n results again, all of them when n
+// is less than one, skipping the cache. Tickets still sold take their new
+// price, results with a ticket whose flights are no longer sold are marked
+// SoldOut and left unverified, as are results whose tickets couldn't be
+// searched, with a warning saying why. req is the request the results were
+// found with, for its passengers and currency. An error is only returned when
+// ctx is done.
+func (s *Searcher) Verify(
+	ctx context.Context,
+	req provider.Request,
+	results []search.Result,
+	n int,
+) ([]search.Result, error) {
+	if n < 1 || n > len(results) {
+		n = len(results)
+	}
+
+	verified := make([]search.Result, len(results))
+	copy(verified, results)
+
+	// results often share a ticket, only search each once.
+	fetched := make(map[string][]itinery.Itinery)
+
+	for i := range n {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		r, err := s.verify(ctx, req, verified[i], fetched)
+		if err != nil {
+			r = verified[i]
+			r.Verified = false
+			r.Warnings = append(slices.Clone(r.Warnings), fmt.Sprintf("the price couldn't be checked again: %v", err))
+		}
+		verified[i] = r
+	}
+
+	return verified, nil
+}
+
+func (s *Searcher) verify(
+	ctx context.Context,
+	req provider.Request,
+	r search.Result,
+	fetched map[string][]itinery.Itinery,
+) (search.Result, error) {
+	itins := make([]itinery.Itinery, len(r.Itineries))
+	copy(itins, r.Itineries)
+	// the results passed to Verify share their warnings' backing array.
+	r.Warnings = slices.Clone(r.Warnings)
+
+	now := time.Now()
+	var price money.Money
+
+	for i, itin := range itins {
+		ticketReq := ticketRequest(req, itin)
+
+		k := ticketReq.Key()
+		current, ok := fetched[k]
+		if !ok {
+			var err error
+			if current, err = s.fetch(ctx, ticketReq); err != nil {
+				return search.Result{}, err
+			}
+			fetched[k] = current
+		}
+
+		found := false
+		for _, c := range current {
+			if !sameFlights(itin, c) {
+				continue
+			}
+			if found {
+				if order, err := c.Price.Cmp(itins[i].Price); err != nil || order >= 0 {
+					continue
+				}
+			}
+			itins[i], found = c, true
+		}
+		if !found {
+			r.SoldOut, r.Verified = true, false
+			r.Warnings = append(r.Warnings, fmt.Sprintf(
+				"the %s to %s ticket is no longer sold", itin.Outbound.DepartureAirport, itin.Outbound.ArrivalAirport,
+			))
+			return r, nil
+		}
+		if itins[i].ObservedAt.IsZero() {
+			itins[i].ObservedAt = now
+		}
+
+		var err error
+		if price, err = price.Add(itins[i].Price); err != nil {
+			return search.Result{}, err
+		}
+	}
+
+	change, err := price.Sub(r.Price)
+	if err != nil {
+		return search.Result{}, err
+	}
+	if !change.IsZero() {
+		direction := "up"
+		if change.Amount < 0 {
+			direction = "down"
+		}
+		r.Warnings = append(r.Warnings, fmt.Sprintf(
+			"the price has gone %s from %s to %s since it was found", direction, r.Price, price,
+		))
+	}
+
+	before, err := r.Total()
+	if err != nil {
+		return search.Result{}, err
+	}
+
+	r.Itineries = itins
+	r.Price = price
+	r.PriceChange = change
+	if r, err = s.rebag(ctx, req, r); err != nil {
+		return search.Result{}, err
+	}
+
+	// the expected cost of rebooking depends only on the flights, which
+	// haven't changed, so the risk adjusted price moves with the total.
+	if !r.RiskAdjustedPrice.IsZero() {
+		after, err := r.Total()
+		if err != nil {
+			return search.Result{}, err
+		}
+		moved, err := after.Sub(before)
+		if err != nil {
+			return search.Result{}, err
+		}
+		if r.RiskAdjustedPrice, err = r.RiskAdjustedPrice.Add(moved); err != nil {
+			return search.Result{}, err
+		}
+	}
+	r.Verified, r.VerifiedAt = true, now
+	r.SoldOut = false
+	r.ObservedAt = now
+	for _, itin := range itins {
+		if itin.ObservedAt.Before(r.ObservedAt) {
+			r.ObservedAt = itin.ObservedAt
+		}
+	}
+
+	return r, nil
+}
+
+// rebag prices r's bags again for its new tickets, whose fares may include
+// different allowances, without repeating warnings r already has.
+func (s *Searcher) rebag(ctx context.Context, req provider.Request, r search.Result) (search.Result, error) {
+	warnings := r.Warnings
+	r.Warnings = nil
+
+	r, err := baggage.Apply(ctx, r, req.Bags, req.Seated(), s.Converter)
+	if err != nil {
+		return search.Result{}, err
+	}
+
+	for _, w := range r.Warnings {
+		if !slices.Contains(warnings, w) {
+			warnings = append(warnings, w)
+		}
+	}
+	r.Warnings = warnings
+	return r, nil
+}
@@ -0,0 +1,202 @@
+package via
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/baggage"
+	"github.com/tobyrushton/flyvia/packages/search/combine"
+	"github.com/tobyrushton/flyvia/packages/search/emissions"
+	"github.com/tobyrushton/flyvia/packages/search/fx"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/loyalty"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"github.com/tobyrushton/flyvia/packages/search/provider"
+	"github.com/tobyrushton/flyvia/packages/search/visa"
+)
+
+// Searcher runs split-ticket searches through a hub. Each ticket is cached so
+// searching several destinations through the same hub only searches the first
+// ticket once.
+type Searcher struct {
+	p provider.Provider
+
+	MinLayover time.Duration
+	MaxLayover time.Duration
+
+	// the most entering a hub can require before trips through it are
+	// dropped, checked against the request's nationalities. Zero keeps every
+	// trip, with warnings for those needing a visa or ETA.
+	Entry visa.Requirement
+
+	// what a hotel costs the party for a night, for connections through the
+	// night. Zero leaves hotels out of the total. Converted to the requested
+	// currency when there's a Converter, otherwise it has to be in it.
+	HotelPerNight money.Money
+
+	// when set, tickets priced in another currency are converted to the
+	// requested one at today's rate before being combined.
+	Converter *fx.Converter
+
+	// the member's frequent flyer programmes, such as "BAEC", to estimate
+	// earnings in. Empty leaves earnings out.
+	Programmes []string
+
+	// when set, every result gets its chance of missing a connection and its
+	// risk-adjusted price. Nil leaves both zero.
+	Risk Assessor
+
+	mu    sync.Mutex
+	cache map[string][]itinery.Itinery
+}
+
+// Assessor prices in the risk of missing a connection between tickets, such
+// as a risk.Model.
+type Assessor interface {
+	Assess(ctx context.Context, r search.Result) (search.Result, error)
+}
+
+func New(p provider.Provider, minLayover, maxLayover time.Duration) *Searcher {
+	return &Searcher{
+		p:          p,
+		MinLayover: minLayover,
+		MaxLayover: maxLayover,
+		cache:      make(map[string][]itinery.Itinery),
+	}
+}
+
+// Search finds trips from req.Origin to req.Destination made of one ticket to
+// the hub and a separate ticket from the hub onwards.
+//
+// cabins optionally sets the class of the ticket to the hub then the one from
+// it, such as economy to position and business on the long-haul. Tickets
+// without one, or with a zero class, use req.Class.
+func (s *Searcher) Search(
+	ctx context.Context,
+	req provider.Request,
+	hub string,
+	cabins ...provider.Class,
+) ([]search.Result, error) {
+	toHub := req
+	toHub.Destination = hub
+	toHub.Class = cabin(req, cabins, 0)
+
+	fromHub := req
+	fromHub.Origin = hub
+	fromHub.Class = cabin(req, cabins, 1)
+
+	first, err := s.Ticket(ctx, toHub)
+	if err != nil {
+		return nil, err
+	}
+	if len(first) == 0 {
+		return []search.Result{}, nil
+	}
+
+	second, err := s.Ticket(ctx, fromHub)
+	if err != nil {
+		return nil, err
+	}
+
+	minLayover := combine.MinLayover(s.MinLayover, req)
+	// without a converter tickets can come back in different currencies, the
+	// pairs that can be totalled are still worth returning.
+	results, mismatch := combine.OneStop(first, second, minLayover, s.MaxLayover)
+	if mismatch != nil && (!errors.Is(mismatch, money.ErrCurrencyMismatch) || len(results) == 0) {
+		return nil, mismatch
+	}
+
+	// a hotel that can't be converted is left out by Classify, with a warning.
+	hotel := s.HotelPerNight
+	if s.Converter != nil && !hotel.IsZero() && hotel.Currency != req.Currency {
+		if converted, err := s.Converter.Convert(ctx, hotel, req.Currency, time.Now()); err == nil {
+			hotel = converted
+		}
+	}
+
+	for i, r := range results {
+		if mismatch != nil {
+			r.Warnings = append(r.Warnings, fmt.Sprintf("some connections through %s were left out as their tickets are priced in different currencies", hub))
+		}
+		if r, err = baggage.Apply(ctx, r, req.Bags, req.Seated(), s.Converter); err != nil {
+			return nil, err
+		}
+		r = emissions.Apply(search.Classify(r, hotel))
+		if len(s.Programmes) > 0 {
+			r = loyalty.Earn(r, s.Programmes...)
+		}
+		if s.Risk != nil {
+			assessed, err := s.Risk.Assess(ctx, r)
+			if err != nil {
+				r.Warnings = append(slices.Clone(r.Warnings), fmt.Sprintf("risk of missing the connection not assessed: %v", err))
+			} else {
+				r = assessed
+			}
+		}
+		results[i] = r
+	}
+
+	if len(req.Nationalities) > 0 {
+		allowed := s.Entry
+		if allowed == 0 {
+			allowed = visa.Visa
+		}
+		results = visa.Filter(results, req.Nationalities, allowed)
+	}
+
+	return results, nil
+}
+
+// Ticket searches a single ticket, using the cache when the same ticket has
+// already been searched for.
+func (s *Searcher) Ticket(
+	ctx context.Context,
+	req provider.Request,
+) ([]itinery.Itinery, error) {
+	k := req.Key()
+
+	s.mu.Lock()
+	itins, ok := s.cache[k]
+	s.mu.Unlock()
+	if ok {
+		return itins, nil
+	}
+
+	return s.fetch(ctx, req)
+}
+
+// fetch searches a ticket from the provider, replacing any cached search.
+func (s *Searcher) fetch(
+	ctx context.Context,
+	req provider.Request,
+) ([]itinery.Itinery, error) {
+	itins, err := s.p.Search(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Converter != nil {
+		itins, err = s.Converter.Itineries(ctx, itins, req.Currency, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	s.cache[req.Key()] = itins
+	s.mu.Unlock()
+
+	return itins, nil
+}
+
+func cabin(req provider.Request, cabins []provider.Class, ticket int) provider.Class {
+	if ticket < len(cabins) && cabins[ticket] != 0 {
+		return cabins[ticket]
+	}
+	return req.Class
+}
@@ -0,0 +1,341 @@
+package via
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tobyrushton/flyvia/packages/search/baggage"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+	"github.com/tobyrushton/flyvia/packages/search/money"
+	"github.com/tobyrushton/flyvia/packages/search/provider"
+	"github.com/tobyrushton/flyvia/packages/search/provider/providertest"
+	"github.com/tobyrushton/flyvia/packages/search/visa"
+	"golang.org/x/text/currency"
+)
+
+var day = time.Date(2030, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// economy fares, premium cabins cost a multiple of them.
+var fares = map[[2]string]float64{
+	{"LHR", "DOH"}: 300,
+	{"DOH", "BKK"}: 400,
+}
+
+var multiplier = map[provider.Class]float64{
+	provider.Economy:        1,
+	provider.PremiumEconomy: 1.5,
+	provider.Business:       3,
+}
+
+type fakeFares struct {
+	classes map[[2]string]provider.Class
+	// routes whose fares have gone up since, or sold out.
+	rises   map[[2]string]float64
+	soldOut map[[2]string]bool
+	// searches to fail before the rest succeed.
+	fails int
+	// airline selling the DOH to BKK flight on a light fare, as a codeshare
+	// of Qatar's, when set.
+	codeshare string
+}
+
+// search returns one-way tickets, to DOH arriving at 12:00 and from DOH
+// leaving at 14:00.
+func (f *fakeFares) search(
+	_ context.Context,
+	req provider.Request,
+) ([]itinery.Itinery, error) {
+	route := [2]string{req.Origin, req.Destination}
+	f.classes[route] = req.Class
+	if f.fails > 0 {
+		f.fails--
+		return nil, errors.New("search failed")
+	}
+	if f.soldOut[route] {
+		return nil, nil
+	}
+
+	dep := req.DepartureDate.Add(8 * time.Hour)
+	if req.Origin == "DOH" {
+		dep = req.DepartureDate.Add(14 * time.Hour)
+	}
+
+	flight := leg.Flight{
+		DepartureTime:    dep,
+		ArrivalTime:      dep.Add(4 * time.Hour),
+		DepartureAirport: req.Origin,
+		ArrivalAirport:   req.Destination,
+		FlightCode:       "QR" + req.Origin,
+		AirlineCode:      "QR",
+		Cabin:            req.Class,
+	}
+	var conditions itinery.Conditions
+	if f.codeshare != "" && req.Origin == "DOH" {
+		flight.FlightCode, flight.AirlineCode = f.codeshare+"6012", f.codeshare
+		flight.OperatingAirline, flight.OperatingAirlineCode = "Qatar Airways", "QR"
+		conditions = itinery.Conditions{CarryOn: true}
+	}
+
+	return []itinery.Itinery{{
+		Outbound: leg.Leg{
+			Flights:          []leg.Flight{flight},
+			DepartureAirport: req.Origin,
+			ArrivalAirport:   req.Destination,
+			DepartureTime:    flight.DepartureTime,
+			ArrivalTime:      flight.ArrivalTime,
+		},
+		Price:      money.New(fares[route]*multiplier[req.Class]+f.rises[route], currency.GBP),
+		Conditions: conditions,
+		ObservedAt: time.Now(),
+	}}, nil
+}
+
+func request() provider.Request {
+	return provider.Request{
+		Origin:        "LHR",
+		Destination:   "BKK",
+		DepartureDate: day,
+		Party:         provider.Party{Adults: 1},
+		Currency:      currency.GBP,
+		Class:         provider.Economy,
+	}
+}
+
+func TestSearch_CabinPerTicket(t *testing.T) {
+	f := &fakeFares{classes: make(map[[2]string]provider.Class)}
+	p := &providertest.Provider{SearchFunc: f.search}
+	s := New(p, time.Hour, 6*time.Hour)
+
+	results, err := s.Search(context.Background(), request(), "DOH", 0, provider.Business)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.classes[[2]string{"LHR", "DOH"}] != provider.Economy {
+		t.Errorf("expected the positioning ticket in the request's class, got %s", f.classes[[2]string{"LHR", "DOH"}])
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	if cabin := results[0].Itineries[1].Outbound.Flights[0].Cabin; cabin != provider.Business {
+		t.Errorf("expected the long-haul flight in business, got %s", cabin)
+	}
+	if results[0].Price != money.New(1500, currency.GBP) {
+		t.Errorf("expected 300 + 1200, got %s", results[0].Price)
+	}
+}
+
+func TestSearch_BagFeesInAnotherCurrency(t *testing.T) {
+	f := &fakeFares{classes: make(map[[2]string]provider.Class)}
+	p := &providertest.Provider{SearchFunc: f.search}
+	s := New(p, time.Hour, 6*time.Hour)
+
+	// Qatar includes one checked bag and charges for the second in USD, while
+	// the search is in GBP.
+	req := request()
+	req.Bags = baggage.Bags{Checked: 2}
+
+	results, err := s.Search(context.Background(), req, "DOH")
+	if err != nil {
+		t.Fatalf("expected bag fees that can't be converted not to fail the search, got %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	if !results[0].BagFees.IsZero() || len(results[0].Warnings) == 0 {
+		t.Errorf("expected the EUR fee to be left out with a warning, got %s and %v", results[0].BagFees, results[0].Warnings)
+	}
+}
+
+func TestUpgrades(t *testing.T) {
+	f := &fakeFares{classes: make(map[[2]string]provider.Class)}
+	p := &providertest.Provider{SearchFunc: f.search}
+	s := New(p, time.Hour, 6*time.Hour)
+
+	results, err := s.Search(context.Background(), request(), "DOH", provider.Economy, provider.PremiumEconomy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upgrades, err := s.Upgrades(context.Background(), request(), results[0], provider.Business)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(upgrades) != 2 {
+		t.Fatalf("expected an upgrade per ticket, got %d", len(upgrades))
+	}
+	if !upgrades[0].Available || upgrades[0].Cost != money.New(600, currency.GBP) {
+		t.Errorf("expected business to the hub to cost 600 more, got %s", upgrades[0].Cost)
+	}
+	if upgrades[1].From != provider.PremiumEconomy || upgrades[1].Cost != money.New(600, currency.GBP) {
+		t.Errorf("expected premium economy to business from the hub to cost 600 more, got %s from %s", upgrades[1].Cost, upgrades[1].From)
+	}
+
+	// already there, nothing to upgrade.
+	upgrades, err = s.Upgrades(context.Background(), request(), results[0], provider.PremiumEconomy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(upgrades) != 1 || upgrades[0].Ticket != 0 {
+		t.Errorf("expected only the economy ticket to be upgradable, got %+v", upgrades)
+	}
+}
+
+func TestSearch_DropsStopsNeedingVisa(t *testing.T) {
+	f := &fakeFares{classes: make(map[[2]string]provider.Class)}
+	p := &providertest.Provider{SearchFunc: f.search}
+	s := New(p, time.Hour, 6*time.Hour)
+	s.Entry = visa.ETA
+
+	req := request()
+	req.Nationalities = [][]string{{"IN"}}
+
+	results, err := s.Search(context.Background(), req, "DOH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Errorf("expected the DOH stop to be dropped for an Indian passport, got %d results", len(results))
+	}
+
+	req.Nationalities = [][]string{{"GB"}}
+	results, err = s.Search(context.Background(), req, "DOH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || len(results[0].Warnings) != 0 {
+		t.Errorf("expected DOH to be open to a British passport, got %+v", results)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	f := &fakeFares{classes: make(map[[2]string]provider.Class)}
+	p := &providertest.Provider{SearchFunc: f.search}
+	s := New(p, time.Hour, 6*time.Hour)
+
+	results, err := s.Search(context.Background(), request(), "DOH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].ObservedAt.IsZero() {
+		t.Error("expected the result to carry when it was priced")
+	}
+
+	f.rises = map[[2]string]float64{{"DOH", "BKK"}: 25}
+	calls := p.Searches()
+	verified, err := s.Verify(context.Background(), request(), results, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Searches() != calls+2 {
+		t.Errorf("expected both tickets to be searched again skipping the cache, got %d searches", p.Searches()-calls)
+	}
+	r := verified[0]
+	if !r.Verified || r.VerifiedAt.IsZero() {
+		t.Errorf("expected the result to be verified, got %+v", r)
+	}
+	if r.Price != money.New(725, currency.GBP) || r.PriceChange != money.New(25, currency.GBP) {
+		t.Errorf("expected the price to go up 25 to 725, got %s up %s", r.Price, r.PriceChange)
+	}
+	if len(r.Warnings) != 1 {
+		t.Errorf("expected a warning about the price change, got %v", r.Warnings)
+	}
+	if results[0].Verified {
+		t.Error("expected the results passed in to be left as they were")
+	}
+
+	f.soldOut = map[[2]string]bool{{"LHR", "DOH"}: true}
+	verified, err = s.Verify(context.Background(), request(), results, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verified[0].SoldOut || verified[0].Verified {
+		t.Errorf("expected the result to be marked sold out, got %+v", verified[0])
+	}
+
+	// the hub's flight now sold under another airline's number, on a fare
+	// without a checked bag.
+	f.soldOut, f.rises, f.codeshare = nil, nil, "BA"
+	results[0].RiskAdjustedPrice = money.New(800, currency.GBP)
+	req := request()
+	req.Bags = baggage.Bags{Checked: 1}
+	verified, err = s.Verify(context.Background(), req, results, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = verified[0]
+	if r.SoldOut || !r.Verified || r.Itineries[1].Outbound.Flights[0].FlightCode != "BA6012" {
+		t.Fatalf("expected the codeshare to be taken as the same flight, got %+v", r)
+	}
+	if r.BagFees != money.New(65, currency.GBP) {
+		t.Errorf("expected the bag the codeshare's fare leaves out to cost 65, got %s", r.BagFees)
+	}
+	if r.RiskAdjustedPrice != money.New(865, currency.GBP) {
+		t.Errorf("expected the risk adjusted price to move with the bags to 865, got %s", r.RiskAdjustedPrice)
+	}
+}
+
+func TestVerify_SkipsFailedSearches(t *testing.T) {
+	f := &fakeFares{classes: make(map[[2]string]provider.Class)}
+	p := &providertest.Provider{SearchFunc: f.search}
+	s := New(p, time.Hour, 6*time.Hour)
+
+	results, err := s.Search(context.Background(), request(), "DOH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results = append(results, results[0])
+
+	f.fails = 1
+	verified, err := s.Verify(context.Background(), request(), results, 0)
+	if err != nil {
+		t.Fatalf("expected a failed search not to fail verifying, got %v", err)
+	}
+	if verified[0].Verified || len(verified[0].Warnings) != 1 {
+		t.Errorf("expected the first result to be left unverified with a warning, got %+v", verified[0])
+	}
+	if !verified[1].Verified {
+		t.Errorf("expected the second result to still be verified, got %+v", verified[1])
+	}
+}
+
+func TestVerify_Repeated(t *testing.T) {
+	f := &fakeFares{classes: make(map[[2]string]provider.Class)}
+	p := &providertest.Provider{SearchFunc: f.search}
+	s := New(p, time.Hour, 6*time.Hour)
+
+	results, err := s.Search(context.Background(), request(), "DOH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// room to append without copying, as a caller's warnings may have.
+	results[0].Warnings = append(make([]string, 0, 4), "found through DOH")
+
+	f.rises = map[[2]string]float64{{"DOH", "BKK"}: 25}
+	first, err := s.Verify(context.Background(), request(), results, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.rises = map[[2]string]float64{{"DOH", "BKK"}: 50}
+	second, err := s.Verify(context.Background(), request(), results, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(second[0].Warnings) != 2 {
+		t.Errorf("expected only the found warning and this run's price change, got %v", second[0].Warnings)
+	}
+	if !strings.Contains(first[0].Warnings[1], "725") {
+		t.Errorf("expected the first run's warning to be left alone, got %v", first[0].Warnings)
+	}
+	if len(results[0].Warnings) != 1 {
+		t.Errorf("expected the results passed in to keep their warnings, got %v", results[0].Warnings)
+	}
+}
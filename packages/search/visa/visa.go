@@ -0,0 +1,213 @@
+package visa
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/airport"
+	"github.com/tobyrushton/flyvia/packages/search/internal/csvdata"
+)
+
+// Requirement is what a traveller needs to enter a country, ordered from
+// least to most.
+type Requirement int64
+
+const (
+	None Requirement = iota + 1
+	ETA
+	Visa
+)
+
+func (r Requirement) String() string {
+	switch r {
+	case None:
+		return "none"
+	case ETA:
+		return "eta"
+	case Visa:
+		return "visa"
+	}
+	return "unknown"
+}
+
+// rules can name a group of countries, prefixed with @, in place of a
+// nationality or country.
+var groups = map[string][]string{
+	"@EU": {
+		"AT", "BE", "BG", "HR", "CY", "CZ", "DK", "EE", "FI", "FR", "DE", "GR", "HU", "IE",
+		"IT", "LV", "LT", "LU", "MT", "NL", "PL", "PT", "RO", "SK", "SI", "ES", "SE",
+	},
+	"@SCHENGEN": {
+		"AT", "BE", "BG", "HR", "CZ", "DK", "EE", "FI", "FR", "DE", "GR", "HU", "IS", "IT",
+		"LV", "LI", "LT", "LU", "MT", "NL", "NO", "PL", "PT", "RO", "SK", "SI", "ES", "SE", "CH",
+	},
+}
+
+// matches any nationality.
+const anyone = "*"
+
+type key struct {
+	nationality, country string
+}
+
+//go:embed rules.csv
+var rulesCSV string
+
+var rules = csvdata.Embed("visa", rulesCSV, parse)
+
+// Load replaces the rules with those read from r, in the same csv format as
+// the embedded rules.csv. The embedded rules are a rough guide to entering
+// the country landside, which is what a self-transfer means, and should be
+// checked against official advice.
+func Load(r io.Reader) error {
+	return rules.Load(r)
+}
+
+func parse(r io.Reader) (map[key]Requirement, error) {
+	parsed := make(map[key]Requirement)
+	err := csvdata.Rows(r, 3, func(line int, rec []string) error {
+		var req Requirement
+		switch rec[2] {
+		case "none":
+			req = None
+		case "eta":
+			req = ETA
+		case "visa":
+			req = Visa
+		default:
+			return fmt.Errorf("line %d: unknown requirement %q", line, rec[2])
+		}
+
+		parsed[key{strings.ToUpper(rec[0]), strings.ToUpper(rec[1])}] = req
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return parsed, nil
+}
+
+// Entry returns what a national of one country needs to enter another, both
+// ISO 3166-1 alpha-2 codes, false when there's no rule for the country.
+// Rules for the nationality win over rules for a group it's in, which win
+// over rules for anyone.
+func Entry(nationality, country string) (Requirement, bool) {
+	nationality, country = strings.ToUpper(nationality), strings.ToUpper(country)
+	if nationality == country {
+		return None, true
+	}
+
+	known := rules.Get()
+	nationalities := append(append([]string{nationality}, groupsOf(nationality)...), anyone)
+	countries := append([]string{country}, groupsOf(country)...)
+
+	for _, n := range nationalities {
+		for _, c := range countries {
+			if req, ok := known[key{n, c}]; ok {
+				return req, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func groupsOf(country string) []string {
+	in := make([]string, 0)
+	for name, members := range groups {
+		if slices.Contains(members, strings.ToUpper(country)) {
+			in = append(in, name)
+		}
+	}
+	// map order is random, keep lookups repeatable.
+	slices.Sort(in)
+	return in
+}
+
+// Party returns the most any traveller needs to enter country, each given by
+// the nationalities of their passports. A traveller with several travels on
+// whichever needs least, the party needs the most of any of them. False when
+// a traveller has no rule for any of their passports, the requirement is then
+// the most of those with one.
+func Party(travellers [][]string, country string) (Requirement, bool) {
+	var strictest Requirement
+	found := true
+	for _, passports := range travellers {
+		var easiest Requirement
+		for _, n := range passports {
+			if req, ok := Entry(n, country); ok && (easiest == 0 || req < easiest) {
+				easiest = req
+			}
+		}
+		if easiest == 0 {
+			found = false
+			continue
+		}
+		strictest = max(strictest, easiest)
+	}
+	return strictest, found
+}
+
+// Check returns r with a warning for every stop between tickets the party
+// needs an ETA or visa for, or that there's no rule for, and the most any
+// stop needs.
+func Check(r search.Result, travellers [][]string) (search.Result, Requirement) {
+	strictest := None
+	warned := make(map[string]bool)
+
+	for _, stop := range stops(r) {
+		a, ok := airport.Lookup(stop)
+		if !ok || warned[a.Country] {
+			continue
+		}
+
+		req, known := Party(travellers, a.Country)
+		if !known {
+			warned[a.Country] = true
+			r.Warnings = append(slices.Clone(r.Warnings), fmt.Sprintf(
+				"no rule for entering %s, check what changing tickets at %s needs",
+				a.Country, a.Code,
+			))
+		}
+		if req == 0 || req == None {
+			continue
+		}
+
+		warned[a.Country] = true
+		strictest = max(strictest, req)
+		r.Warnings = append(slices.Clone(r.Warnings), fmt.Sprintf(
+			"changing tickets at %s means entering %s, which needs a %s",
+			a.Code, a.Country, strings.ToUpper(req.String()),
+		))
+	}
+
+	return r, strictest
+}
+
+// Filter checks every result, dropping those with a stop needing more than
+// allowed.
+func Filter(results []search.Result, travellers [][]string, allowed Requirement) []search.Result {
+	kept := make([]search.Result, 0, len(results))
+	for _, r := range results {
+		checked, req := Check(r, travellers)
+		if req > allowed {
+			continue
+		}
+		kept = append(kept, checked)
+	}
+	return kept
+}
+
+// stops are the airports where one ticket ends and the next begins, a pair of
+// return tickets changes at the same hub both ways.
+func stops(r search.Result) []string {
+	s := make([]string, 0, len(r.Itineries))
+	for i := 0; i < len(r.Itineries)-1; i++ {
+		s = append(s, r.Itineries[i].Outbound.ArrivalAirport)
+	}
+	return s
+}
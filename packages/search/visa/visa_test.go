@@ -0,0 +1,102 @@
+package visa
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tobyrushton/flyvia/packages/search"
+	"github.com/tobyrushton/flyvia/packages/search/itinery"
+	"github.com/tobyrushton/flyvia/packages/search/leg"
+)
+
+func TestEntry(t *testing.T) {
+	tests := []struct {
+		nationality, country string
+		want                 Requirement
+	}{
+		{"GB", "FR", None},
+		{"IN", "FR", Visa},
+		{"DE", "US", ETA},
+		// a nationality rule wins over the EU group.
+		{"BG", "US", Visa},
+		{"us", "us", None},
+		{"IN", "SG", Visa},
+		{"BR", "SG", None},
+	}
+
+	for _, tt := range tests {
+		got, ok := Entry(tt.nationality, tt.country)
+		if !ok || got != tt.want {
+			t.Errorf("%s into %s: expected %s, got %s", tt.nationality, tt.country, tt.want, got)
+		}
+	}
+
+	if _, ok := Entry("GB", "ZZ"); ok {
+		t.Error("expected no rule for an unknown country")
+	}
+}
+
+func TestParty(t *testing.T) {
+	tests := []struct {
+		name       string
+		travellers [][]string
+		country    string
+		want       Requirement
+	}{
+		{"dual national uses the easier passport", [][]string{{"IN", "GB"}}, "FR", None},
+		{"strictest of the travellers", [][]string{{"GB"}, {"IN"}}, "FR", Visa},
+		{"dual national with a stricter companion", [][]string{{"IN", "FR"}, {"DE", "US"}}, "US", ETA},
+	}
+
+	for _, tt := range tests {
+		got, ok := Party(tt.travellers, tt.country)
+		if !ok || got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+
+	if _, ok := Party([][]string{{"GB"}, {"US"}}, "ZZ"); ok {
+		t.Error("expected no rule for a country without one")
+	}
+}
+
+func stopAt(hub string) search.Result {
+	r, _ := search.NewResult(
+		itinery.Itinery{Outbound: leg.Leg{DepartureAirport: "LHR", ArrivalAirport: hub}},
+		itinery.Itinery{Outbound: leg.Leg{DepartureAirport: hub, ArrivalAirport: "BKK"}},
+	)
+	return r
+}
+
+func TestCheck(t *testing.T) {
+	r, req := Check(stopAt("JFK"), [][]string{{"FR"}})
+	if req != ETA {
+		t.Errorf("expected an ETA to change at JFK, got %s", req)
+	}
+	if len(r.Warnings) != 1 || !strings.Contains(r.Warnings[0], "ETA") {
+		t.Errorf("expected a warning about the ETA, got %v", r.Warnings)
+	}
+
+	if r, req := Check(stopAt("CDG"), [][]string{{"GB"}}); req != None || len(r.Warnings) != 0 {
+		t.Errorf("expected nothing needed at CDG, got %s with %v", req, r.Warnings)
+	}
+
+	r, req = Check(stopAt("CAI"), [][]string{{"GB"}})
+	if req != None || len(r.Warnings) != 1 || !strings.Contains(r.Warnings[0], "no rule for entering EG") {
+		t.Errorf("expected a warning there's no rule for Egypt, got %s with %v", req, r.Warnings)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	results := []search.Result{stopAt("DOH"), stopAt("IST"), stopAt("DUB")}
+
+	kept := Filter(results, [][]string{{"IN"}}, ETA)
+	if len(kept) != 0 {
+		t.Errorf("expected every stop to need a visa for an Indian passport, kept %d", len(kept))
+	}
+
+	kept = Filter(results, [][]string{{"US"}}, None)
+	if len(kept) != 3 {
+		t.Errorf("expected every stop to be open to a US passport, kept %d", len(kept))
+	}
+}